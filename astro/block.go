@@ -0,0 +1,141 @@
+package astro
+
+import (
+	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/utils"
+
+	"fmt"
+	"math"
+	"time"
+)
+
+// Block shows a countdown to the sun's next event and the moon's phase,
+// calculated without the network
+type Block struct {
+	latitude  float64
+	longitude float64
+
+	now        time.Time
+	nextUpdate time.Time
+}
+
+// NewAstroBlock returns a new astro.Block for a latitude and longitude in
+// degrees, north and east positive
+func NewAstroBlock(latitude, longitude float64) (*Block, error) {
+	if math.Abs(latitude) > 90 || math.Abs(longitude) > 180 {
+		return nil, fmt.Errorf("astro: invalid location: %g,%g", latitude, longitude)
+	}
+
+	return &Block{latitude: latitude, longitude: longitude}, nil
+}
+
+func (b *Block) StartBroadcast() <-chan bool {
+	c := make(chan bool)
+	go b.broadcast(c)
+	return c
+}
+
+func (b *Block) broadcast(c chan<- bool) {
+	for {
+		b.Update()
+		c <- true
+		time.Sleep(time.Until(b.nextUpdate))
+	}
+}
+
+// Update updates the time the block is shown for. the countdown is in
+// minutes, so it's updated every minute
+func (b *Block) Update() {
+	b.now = time.Now()
+	b.nextUpdate = b.now.Add(time.Minute).Truncate(time.Minute)
+}
+
+// Daytime returns true if the sun is up. ok is always true, since the sun is
+// calculated rather than reported
+func (b *Block) Daytime() (day, ok bool) {
+	return isDaytime(time.Now(), b.latitude, b.longitude), true
+}
+
+// Name returns "astro"
+func (b *Block) Name() string {
+	return "astro"
+}
+
+// Icon returns the moon's phase
+func (b *Block) Icon() rune {
+	return moonIcon(moonPhase(b.now))
+}
+
+// Text returns a countdown to the sun's next event, like "Sunset in 2h 14m",
+// and the moon's phase
+func (b *Block) Text() (primary, secondary string) {
+	phase := moonPhase(b.now)
+	secondary = fmt.Sprintf("%s, %.0f%% lit", utils.Capitalize(phaseName(phase)), moonIllumination(phase)*100)
+
+	e, at, ok := nextEvent(b.now, b.latitude, b.longitude)
+	if !ok {
+		if isDaytime(b.now, b.latitude, b.longitude) {
+			return "Midnight sun", secondary
+		}
+		return "Polar night", secondary
+	}
+
+	return fmt.Sprintf("%s in %s", utils.Capitalize(e.String()), formatCountdown(at.Sub(b.now))), secondary
+}
+
+// Colorer returns the default colorer
+func (b *Block) Colorer() format.Colorer {
+	return format.GetDefaultColorer()
+}
+
+// Hidden returns false
+func (b *Block) Hidden() bool {
+	return false
+}
+
+// ForceShort returns false
+func (b *Block) ForceShort() bool {
+	return false
+}
+
+func (b *Block) Output(mode format.Mode) string {
+	return format.FormatClassicBlock(b)
+}
+
+// Query returns today's events, the next one, and the moon's phase
+func (b *Block) Query() map[string]interface{} {
+	now := time.Now()
+	phase := moonPhase(now)
+
+	info := map[string]interface{}{
+		"latitude":          b.latitude,
+		"longitude":         b.longitude,
+		"daytime":           isDaytime(now, b.latitude, b.longitude),
+		"moon_phase":        phaseName(phase),
+		"moon_cycle":        phase,
+		"moon_illumination": moonIllumination(phase),
+		"moon_age_days":     phase * synodicMonth,
+	}
+
+	for event, at := range sunTimes(now, b.latitude, b.longitude) {
+		if !at.IsZero() {
+			info[Event(event).String()] = at.Format(time.RFC3339)
+		}
+	}
+
+	if e, at, ok := nextEvent(now, b.latitude, b.longitude); ok {
+		info["next_event"] = e.String()
+		info["next_event_time"] = at.Format(time.RFC3339)
+	}
+
+	return info
+}
+
+// formatCountdown returns a duration like "2h 14m", rounded up to the minute
+func formatCountdown(d time.Duration) string {
+	minutes := int(math.Ceil(d.Minutes()))
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
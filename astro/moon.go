@@ -0,0 +1,69 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	synodicMonth = 29.530588853 // days from one new moon to the next
+)
+
+// the moon's phases in the same order as phaseNames
+var moonIcons = []rune{'\uff64', '\uff67', '\uff61', '\uff68', '\uff62', '\uff66', '\uff63', '\uff65'}
+
+// nerd font icons, starting with the new moon, then six waxing crescents,
+// the first quarter, and so on
+// const (
+// 	firstMoonIcon = '\ue38d'
+// 	moonIconCount = 28
+// )
+
+// a new moon to count the others from
+var knownNewMoon = time.Date(2000, time.January, 6, 18, 14, 0, 0, time.UTC)
+
+var phaseNames = []string{
+	"new moon",
+	"waxing crescent",
+	"first quarter",
+	"waxing gibbous",
+	"full moon",
+	"waning gibbous",
+	"last quarter",
+	"waning crescent",
+}
+
+// moonPhase returns how far through its cycle the moon is at t, from 0 (new)
+// through 0.5 (full) to 1. it's the mean phase, so it can be up to about
+// 14 hours off
+func moonPhase(t time.Time) float64 {
+	days := t.Sub(knownNewMoon).Hours() / 24
+	phase := math.Mod(days/synodicMonth, 1)
+	if phase < 0 {
+		phase++
+	}
+	return phase
+}
+
+// moonIllumination returns how much of the moon is lit at a phase, from 0
+// to 1
+func moonIllumination(phase float64) float64 {
+	return (1 - math.Cos(2*math.Pi*phase)) / 2
+}
+
+// phaseIndex returns which of the eight named phases a phase is closest to
+func phaseIndex(phase float64) int {
+	return int(math.Round(phase*float64(len(phaseNames)))) % len(phaseNames)
+}
+
+// phaseName returns the name of a phase, like "waxing gibbous"
+func phaseName(phase float64) string {
+	return phaseNames[phaseIndex(phase)]
+}
+
+// moonIcon returns the icon for a phase
+func moonIcon(phase float64) rune {
+	// nerd font
+	// return firstMoonIcon + rune(int(math.Round(phase*moonIconCount))%moonIconCount)
+	return moonIcons[phaseIndex(phase)]
+}
@@ -0,0 +1,54 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// the mean phase can be about 14 hours off, which is a little under 0.02 of a
+// cycle
+const phaseTolerance = 0.025
+
+func TestMoonPhase(t *testing.T) {
+	for _, test := range []struct {
+		at   time.Time // as published, in UTC
+		want float64
+		name string
+	}{
+		{time.Date(2024, time.January, 11, 11, 57, 0, 0, time.UTC), 0, "new moon"},
+		{time.Date(2024, time.January, 18, 3, 52, 0, 0, time.UTC), 0.25, "first quarter"},
+		{time.Date(2024, time.February, 2, 23, 18, 0, 0, time.UTC), 0.75, "last quarter"},
+		{time.Date(2024, time.April, 8, 18, 21, 0, 0, time.UTC), 0, "new moon"},
+		{time.Date(2024, time.September, 18, 2, 34, 0, 0, time.UTC), 0.5, "full moon"},
+		{time.Date(2023, time.August, 31, 1, 35, 0, 0, time.UTC), 0.5, "full moon"},
+		{time.Date(1999, time.December, 22, 17, 31, 0, 0, time.UTC), 0.5, "full moon"},
+	} {
+		phase := moonPhase(test.at)
+
+		// new moons can land on either side of the wrap
+		diff := math.Abs(phase - test.want)
+		diff = math.Min(diff, 1-diff)
+		if diff > phaseTolerance {
+			t.Errorf("%s: phase is %.3f, want %.3f", test.at.Format("2006-01-02"), phase, test.want)
+		}
+		if name := phaseName(phase); name != test.name {
+			t.Errorf("%s: phase is %q, want %q", test.at.Format("2006-01-02"), name, test.name)
+		}
+	}
+}
+
+func TestMoonIllumination(t *testing.T) {
+	for _, test := range []struct {
+		phase, want float64
+	}{
+		{0, 0},
+		{0.25, 0.5},
+		{0.5, 1},
+		{0.75, 0.5},
+	} {
+		if got := moonIllumination(test.phase); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%v: illumination is %v, want %v", test.phase, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	unixEpochJulian = 2440587.5 // the Julian date of the Unix epoch
+	j2000           = 2451545.0 // the Julian date of 2000-01-01 12:00 UTC
+	axialTilt       = 23.4397   // degrees
+
+	// how far below the horizon the center of the sun is at each event, in
+	// degrees. sunrise and sunset account for refraction and the sun's radius
+	sunriseAltitude  = -0.833
+	twilightAltitude = -6
+)
+
+// Event is something the sun does each day
+type Event int
+
+// Definitions for Event, in the order they happen
+const (
+	Dawn Event = iota // the start of civil twilight
+	Sunrise
+	Sunset
+	Dusk // the end of civil twilight
+)
+
+func (e Event) String() string {
+	switch e {
+	case Dawn:
+		return "dawn"
+	case Sunrise:
+		return "sunrise"
+	case Sunset:
+		return "sunset"
+	case Dusk:
+		return "dusk"
+	default:
+		return "unknown"
+	}
+}
+
+// SunTimes are when the sun's events happen on a day. an event is zero if it
+// doesn't happen that day, like sunrise during a polar night
+type SunTimes [4]time.Time
+
+// julian returns the Julian date of t
+func julian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + unixEpochJulian
+}
+
+// fromJulian returns the time at a Julian date
+func fromJulian(jd float64) time.Time {
+	return time.Unix(int64(math.Round((jd-unixEpochJulian)*86400)), 0)
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// sunTimes returns the sun's events on the day containing t, in t's time
+// zone, at a latitude and longitude in degrees (north and east positive). it
+// uses the sunrise equation, which is good to about a minute away from the
+// poles
+func sunTimes(t time.Time, lat, lon float64) SunTimes {
+	y, m, d := t.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, t.Location())
+
+	// the solar noon nearest to local noon
+	n := math.Round(julian(noon) - j2000 - 0.0009 + lon/360)
+	approxNoon := j2000 + 0.0009 - lon/360 + n
+
+	anomaly := math.Mod(357.5291+0.98560028*(approxNoon-j2000), 360)
+	center := 1.9148*math.Sin(radians(anomaly)) +
+		0.02*math.Sin(radians(2*anomaly)) +
+		0.0003*math.Sin(radians(3*anomaly))
+	longitude := math.Mod(anomaly+center+180+102.9372, 360)
+
+	transit := approxNoon + 0.0053*math.Sin(radians(anomaly)) - 0.0069*math.Sin(radians(2*longitude))
+	declination := math.Asin(math.Sin(radians(longitude)) * math.Sin(radians(axialTilt)))
+
+	var times SunTimes
+	for _, e := range []struct {
+		rise, set Event
+		altitude  float64
+	}{
+		{Dawn, Dusk, twilightAltitude},
+		{Sunrise, Sunset, sunriseAltitude},
+	} {
+		cosHourAngle := (math.Sin(radians(e.altitude)) - math.Sin(radians(lat))*math.Sin(declination)) /
+			(math.Cos(radians(lat)) * math.Cos(declination))
+		if cosHourAngle < -1 || cosHourAngle > 1 {
+			// the sun stays above or below this altitude all day
+			continue
+		}
+
+		hourAngle := degrees(math.Acos(cosHourAngle))
+		times[e.rise] = fromJulian(transit - hourAngle/360).In(t.Location())
+		times[e.set] = fromJulian(transit + hourAngle/360).In(t.Location())
+	}
+
+	return times
+}
+
+// nextEvent returns the first of the sun's events after t, looking ahead a
+// few days. ok is false if there isn't one, like during a polar night
+func nextEvent(t time.Time, lat, lon float64) (e Event, at time.Time, ok bool) {
+	for day := -1; day <= 2; day++ {
+		times := sunTimes(t.AddDate(0, 0, day), lat, lon)
+		for event, eventTime := range times {
+			if eventTime.After(t) {
+				return Event(event), eventTime, true
+			}
+		}
+	}
+	return
+}
+
+// isDaytime returns true if the sun is up at t. near the poles, where it may
+// not rise or set at all, this is decided by whether it's above the horizon
+// at noon
+func isDaytime(t time.Time, lat, lon float64) bool {
+	times := sunTimes(t, lat, lon)
+	if !times[Sunrise].IsZero() {
+		return !t.Before(times[Sunrise]) && t.Before(times[Sunset])
+	}
+
+	// polar day or night; the sun is up all day if it's in the same
+	// hemisphere as us
+	y, m, d := t.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, t.Location())
+	days := julian(noon) - j2000
+	longitude := math.Mod(280.46+0.9856474*days, 360)
+	return (lat > 0) == (math.Sin(radians(longitude)) > 0)
+}
@@ -0,0 +1,101 @@
+package astro
+
+import (
+	"testing"
+	"time"
+)
+
+// the sunrise equation is good to about a minute; published times are rounded
+// to the minute too
+const sunTolerance = time.Minute * 3
+
+func TestSunTimes(t *testing.T) {
+	edt := time.FixedZone("EDT", -4*60*60)
+	aedt := time.FixedZone("AEDT", 11*60*60)
+
+	for _, test := range []struct {
+		name            string
+		lat, lon        float64
+		date            time.Time
+		sunrise, sunset string // as published, in the date's time zone
+	}{
+		{"new york, june", 40.7128, -74.0060, time.Date(2024, time.June, 20, 12, 0, 0, 0, edt), "05:25", "20:31"},
+		{"london, december", 51.5074, -0.1278, time.Date(2024, time.December, 21, 12, 0, 0, 0, time.UTC), "08:04", "15:53"},
+		{"sydney, december", -33.8688, 151.2093, time.Date(2024, time.December, 21, 12, 0, 0, 0, aedt), "05:41", "20:05"},
+	} {
+		times := sunTimes(test.date, test.lat, test.lon)
+		for _, e := range []struct {
+			event Event
+			want  string
+		}{
+			{Sunrise, test.sunrise},
+			{Sunset, test.sunset},
+		} {
+			clock, _ := time.Parse("15:04", e.want)
+			y, m, d := test.date.Date()
+			want := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, test.date.Location())
+
+			got := times[e.event]
+			if diff := got.Sub(want); diff < -sunTolerance || diff > sunTolerance {
+				t.Errorf("%s: %s is %s, want %s", test.name, e.event, got.Format("15:04"), e.want)
+			}
+		}
+
+		if !times[Dawn].Before(times[Sunrise]) || !times[Dusk].After(times[Sunset]) {
+			t.Errorf("%s: twilight isn't around the day: %v", test.name, times)
+		}
+	}
+}
+
+func TestPolarSun(t *testing.T) {
+	// Tromsø, where the sun doesn't set in June or rise in December, but
+	// still gets close enough to the horizon in December for twilight
+	const lat, lon = 69.6492, 18.9553
+
+	for _, test := range []struct {
+		name     string
+		date     time.Time
+		day      bool
+		twilight bool
+	}{
+		{"polar day", time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC), true, false},
+		{"polar night", time.Date(2024, time.December, 21, 12, 0, 0, 0, time.UTC), false, true},
+	} {
+		times := sunTimes(test.date, lat, lon)
+		if !times[Sunrise].IsZero() || !times[Sunset].IsZero() {
+			t.Errorf("%s: the sun rises at %v and sets at %v", test.name, times[Sunrise], times[Sunset])
+		}
+		if twilight := !times[Dawn].IsZero() && !times[Dusk].IsZero(); twilight != test.twilight {
+			t.Errorf("%s: twilight is %v, want %v", test.name, twilight, test.twilight)
+		}
+		if day := isDaytime(test.date, lat, lon); day != test.day {
+			t.Errorf("%s: daytime is %v, want %v", test.name, day, test.day)
+		}
+
+		e, _, ok := nextEvent(test.date, lat, lon)
+		if ok != test.twilight || (ok && e != Dusk) {
+			t.Errorf("%s: next event is %v (%v)", test.name, e, ok)
+		}
+	}
+}
+
+func TestIsDaytime(t *testing.T) {
+	// London, with sunrise at 08:04 and sunset at 15:53
+	const lat, lon = 51.5074, -0.1278
+
+	for _, test := range []struct {
+		clock string
+		want  bool
+	}{
+		{"07:30", false},
+		{"08:30", true},
+		{"15:30", true},
+		{"16:30", false},
+	} {
+		clock, _ := time.Parse("15:04", test.clock)
+		now := time.Date(2024, time.December, 21, clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+		if got := isDaytime(now, lat, lon); got != test.want {
+			t.Errorf("%s: daytime is %v, want %v", test.clock, got, test.want)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package brightness
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/uevent"
 	"github.com/muni-corn/muse-status/utils"
 	"time"
 )
@@ -29,30 +33,50 @@ type Block struct {
 	icon  rune
 	fader *format.FadingColorer
 
+	changes <-chan struct{}     // inotify on the sysfs files
+	uevents <-chan uevent.Event // backlight uevents, for drivers that don't notify sysfs
+	wake    chan struct{}       // wakes the broadcast after commands
+
 	rapidfire bool
 }
 
-// NewBrightnessBlock returns a new brightness.Block
+// NewBrightnessBlock returns a new brightness.Block. If card is empty, the
+// preferred backlight is detected automatically. The block watches for
+// brightness changes on its own; rapidfire only enables polling for systems
+// where watching isn't possible
 func NewBrightnessBlock(card string, rapidfire bool) (*Block, error) {
-	if rapidfire {
-		// println("WARNING! A brightness block has been created with rapidfire enabled. This can be VERY bad for your system's performance. Try using `muse-status notify volume` instead after volume updates.")
+	var err error
+	if card == "" {
+		card, err = DetectBacklight()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	b := &Block{
 		card:      card,
 		rapidfire: rapidfire,
+		wake:      make(chan struct{}, 1),
 	}
 
-	var err error
 	b.maxBrightness, err = b.getMaxBrightness()
 	if err != nil {
 		return nil, err
 	}
 
-	b.fader = &format.FadingColorer{
-		Duration:   3,
-		StartColor: format.PrimaryColor(),
-		EndColor:   format.SecondaryColor(),
+	b.fader = newFader()
+
+	// watching is best-effort; either source is enough on its own
+	// the backlight stays as long as we do, so it's never unwatched
+	b.changes, _, _ = utils.WatchFiles(b.getDir()+"/brightness", b.getDir()+"/actual_brightness")
+	b.uevents, _ = uevent.Listen("backlight")
+
+	if b.rapidfire && (b.changes != nil || b.uevents != nil) {
+		b.rapidfire = false
+	}
+
+	if b.rapidfire {
+		// println("WARNING! Brightness changes can't be watched on this system, so the brightness block will poll rapidly instead. This can be VERY bad for your system's performance.")
 	}
 
 	return b, nil
@@ -60,25 +84,49 @@ func NewBrightnessBlock(card string, rapidfire bool) (*Block, error) {
 
 func (b *Block) StartBroadcast() <-chan bool {
 	c := make(chan bool)
-	if b.rapidfire {
-		go b.broadcast(c)
-	}
+	go b.broadcast(c)
 	return c
 }
 
 func (b *Block) broadcast(c chan<- bool) {
+	b.Update()
+	b.lastBrightness = b.currentBrightness
+
 	for {
+		// only tick while animating, or if we have no other choice
+		var tick <-chan time.Time
 		if b.fader.IsFading() {
-			c <- true
+			tick = time.After(time.Second / 15)
+		} else if b.rapidfire {
+			tick = time.After(time.Second / 10)
+		}
+
+		select {
+		case _, ok := <-b.changes:
+			if !ok {
+				b.changes = nil
+				continue
+			}
+		case e, ok := <-b.uevents:
+			if !ok {
+				b.uevents = nil
+				continue
+			}
+			if !strings.HasSuffix(e.DevPath, "/"+b.card) {
+				continue
+			}
+		case <-b.wake:
+		case <-tick:
 		}
+
 		b.Update()
 		if b.currentBrightness != b.lastBrightness {
 			b.fader.Trigger()
-			c <- true
 			b.lastBrightness = b.currentBrightness
+			c <- true
+		} else if b.fader.IsFading() {
+			c <- true
 		}
-
-		time.Sleep(time.Second / 10)
 	}
 }
 
@@ -95,8 +143,65 @@ func (b *Block) Update() {
 		return
 	}
 
-	b.text = fmt.Sprintf("%d%%", b.currentBrightness*100/b.maxBrightness)
-	b.icon = getIcon(b.currentBrightness * 100 / b.maxBrightness)
+	percent := int(math.Round(rawToPercent(b.currentBrightness, b.maxBrightness)))
+	b.text = fmt.Sprintf("%d%%", percent)
+	b.icon = getIcon(percent)
+}
+
+// HandleCommand handles `set`, `inc` and `dec` commands, each followed by a
+// percentage, e.g. `brightness inc 5`
+func (b *Block) HandleCommand(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: brightness set|inc|dec <percent>")
+	}
+
+	amount, err := parsePercent(args[1])
+	if err != nil {
+		return err
+	}
+
+	current, err := b.getCurrentBrightness()
+	if err != nil {
+		return err
+	}
+
+	percent := rawToPercent(current, b.maxBrightness)
+	switch args[0] {
+	case "set":
+		percent = amount
+	case "inc":
+		percent += amount
+	case "dec":
+		percent -= amount
+	default:
+		return fmt.Errorf("unknown brightness command: %s", args[0])
+	}
+
+	percent = math.Max(0, math.Min(100, percent))
+	value := percentToRaw(percent, b.maxBrightness)
+
+	// make sure small steps at the bottom of the curve still do something
+	if args[0] == "inc" && value <= current && current < b.maxBrightness {
+		value = current + 1
+	} else if args[0] == "dec" && value >= current && current > 0 {
+		value = current - 1
+	}
+
+	// don't turn the screen off entirely
+	if value < 1 {
+		value = 1
+	}
+
+	if err := setBrightness("backlight", b.card, value); err != nil {
+		return err
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
 }
 
 // Icon returns the brightness icon
@@ -126,12 +231,16 @@ func (b *Block) ForceShort() bool {
 	return false
 }
 
+func (b *Block) getDir() string {
+	return baseDir + b.card
+}
+
 func (b *Block) getMaxBrightness() (value int, err error) {
-	return utils.GetIntFromFile(baseDir + b.card + "/max_brightness")
+	return utils.GetIntFromFile(b.getDir() + "/max_brightness")
 }
 
 func (b *Block) getCurrentBrightness() (value int, err error) {
-	return utils.GetIntFromFile(baseDir + b.card + "/brightness")
+	return utils.GetIntFromFile(b.getDir() + "/brightness")
 }
 
 func (b *Block) Output(mode format.Mode) string {
@@ -1,5 +1,19 @@
 package brightness
 
+import (
+	"github.com/muni-corn/muse-status/format"
+)
+
+// newFader returns the fader that brightness blocks use to stand out for a
+// moment after changing
+func newFader() *format.FadingColorer {
+	return &format.FadingColorer{
+		Duration:   3,
+		StartColor: format.PrimaryColor(),
+		EndColor:   format.SecondaryColor(),
+	}
+}
+
 func getIcon(percentage int) rune {
 	index := percentage * len(brightnessIcons) / 100
 
@@ -0,0 +1,83 @@
+package brightness
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/muni-corn/muse-status/utils"
+)
+
+// curveExponent shapes the perceptual brightness curve. our eyes are much
+// more sensitive to changes at low brightness, so percentages are mapped to
+// raw values exponentially (the same curve as `brightnessctl -e`)
+const curveExponent = 4
+
+// backlight types, in order of preference. firmware interfaces know the most
+// about the panel, while raw ones write straight to the hardware
+var backlightTypePriority = []string{"firmware", "platform", "raw"}
+
+// DetectBacklight returns the name of the preferred backlight device under
+// /sys/class/backlight
+func DetectBacklight() (string, error) {
+	infos, err := ioutil.ReadDir(baseDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, t := range backlightTypePriority {
+		for _, info := range infos {
+			str, err := utils.GetStringFromFile(baseDir + info.Name() + "/type")
+			if err == nil && str == t {
+				return info.Name(), nil
+			}
+		}
+	}
+
+	return "", errors.New("no backlight found")
+}
+
+// rawToPercent converts a raw brightness value to a perceptual percentage
+func rawToPercent(raw, max int) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return 100 * math.Pow(float64(raw)/float64(max), 1.0/curveExponent)
+}
+
+// percentToRaw converts a perceptual percentage to a raw brightness value
+func percentToRaw(percent float64, max int) int {
+	return int(math.Round(math.Pow(percent/100, curveExponent) * float64(max)))
+}
+
+// parsePercent parses a percentage like "5" or "5%"
+func parsePercent(str string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+}
+
+// setBrightness sets the brightness of a device, through logind if possible
+// so that we don't need write access to sysfs. subsystem is "backlight" or
+// "leds"
+func setBrightness(subsystem, device string, value int) error {
+	err := setBrightnessLogind(subsystem, device, value)
+	if err == nil {
+		return nil
+	}
+
+	path := fmt.Sprintf("/sys/class/%s/%s/brightness", subsystem, device)
+	return ioutil.WriteFile(path, []byte(strconv.Itoa(value)), 0644)
+}
+
+func setBrightnessLogind(subsystem, device string, value int) error {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+
+	session := conn.Object("org.freedesktop.login1", "/org/freedesktop/login1/session/auto")
+	return session.Call("org.freedesktop.login1.Session.SetBrightness", 0, subsystem, device, uint32(value)).Err
+}
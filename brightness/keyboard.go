@@ -0,0 +1,255 @@
+package brightness
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/uevent"
+	"github.com/muni-corn/muse-status/utils"
+)
+
+const (
+	ledsDir             = "/sys/class/leds/"
+	kbdBacklightName    = "::kbd_backlight"
+	keyboardIcon        = ''
+	keyboardCycleAction = "muse-status keyboard cycle"
+)
+
+// KeyboardBlock shows the keyboard backlight level. It's hidden when there's
+// no keyboard backlight
+type KeyboardBlock struct {
+	device    string // e.g. "tpacpi::kbd_backlight"; empty if absent
+	level     int
+	lastLevel int
+	maxLevel  int
+
+	fader *format.FadingColorer
+
+	changes      <-chan struct{}     // inotify on the device's sysfs files
+	stopWatching func()              // stops changes; nil without a device
+	uevents      <-chan uevent.Event // leds uevents, for devices coming and going
+	wake         chan struct{}       // wakes the broadcast after commands
+}
+
+// NewKeyboardBlock returns a new KeyboardBlock
+func NewKeyboardBlock() *KeyboardBlock {
+	b := &KeyboardBlock{
+		fader: newFader(),
+		wake:  make(chan struct{}, 1),
+	}
+
+	b.uevents, _ = uevent.Listen("leds")
+	b.findDevice()
+
+	return b
+}
+
+// findDevice looks for a keyboard backlight, and watches it if one is found.
+// it's only called by the broadcast (or before it starts), which is what
+// reads changes
+func (b *KeyboardBlock) findDevice() {
+	if b.stopWatching != nil {
+		b.stopWatching()
+	}
+	b.device = ""
+	b.changes, b.stopWatching = nil, nil
+
+	infos, err := ioutil.ReadDir(ledsDir)
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), kbdBacklightName) {
+			continue
+		}
+
+		max, err := utils.GetIntFromFile(ledsDir + info.Name() + "/max_brightness")
+		if err != nil || max <= 0 {
+			continue
+		}
+
+		b.device = info.Name()
+		b.maxLevel = max
+
+		// brightness_hw_changed notifies of changes made by the firmware,
+		// like Fn key combos
+		dir := b.getDir()
+		b.changes, b.stopWatching, _ = utils.WatchFiles(dir+"/brightness", dir+"/brightness_hw_changed")
+		return
+	}
+}
+
+// StartBroadcast starts broadcasting from this block. It returns a channel
+// that sends output when an update should happen
+func (b *KeyboardBlock) StartBroadcast() <-chan bool {
+	c := make(chan bool)
+	go b.broadcast(c)
+	return c
+}
+
+func (b *KeyboardBlock) broadcast(c chan<- bool) {
+	b.refresh()
+	b.lastLevel = b.level
+	c <- true
+
+	for {
+		// only tick while animating
+		var tick <-chan time.Time
+		if b.fader.IsFading() {
+			tick = time.After(time.Second / 15)
+		}
+
+		hadDevice := b.device != ""
+
+		select {
+		case _, ok := <-b.changes:
+			if !ok {
+				b.changes = nil
+				continue
+			}
+		case e, ok := <-b.uevents:
+			if !ok {
+				b.uevents = nil
+				continue
+			}
+			if !strings.HasSuffix(e.DevPath, kbdBacklightName) {
+				continue
+			}
+			if e.Action == "add" || e.Action == "remove" {
+				b.findDevice()
+			}
+		case <-b.wake:
+		case <-tick:
+		}
+
+		b.refresh()
+		if hadDevice != (b.device != "") {
+			c <- true
+		} else if b.level != b.lastLevel {
+			b.fader.Trigger()
+			b.lastLevel = b.level
+			c <- true
+		} else if b.fader.IsFading() {
+			c <- true
+		}
+	}
+}
+
+// Name returns "keyboard"
+func (b *KeyboardBlock) Name() string {
+	return "keyboard"
+}
+
+// Update reads the backlight level
+func (b *KeyboardBlock) Update() {
+	b.read()
+}
+
+// refresh reads the backlight level, looking for the device again if it's
+// gone away
+func (b *KeyboardBlock) refresh() {
+	if err := b.read(); err != nil {
+		b.findDevice()
+	}
+}
+
+func (b *KeyboardBlock) read() error {
+	if b.device == "" {
+		return nil
+	}
+
+	level, err := utils.GetIntFromFile(b.getDir() + "/brightness")
+	if err != nil {
+		return err
+	}
+
+	b.level = level
+	return nil
+}
+
+// HandleCommand handles `cycle`, which steps through each level and back to
+// off, and `set <level>`
+func (b *KeyboardBlock) HandleCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: keyboard cycle|set <level>")
+	}
+
+	if b.device == "" {
+		return errors.New("no keyboard backlight found")
+	}
+
+	var level int
+	switch args[0] {
+	case "cycle":
+		level = (b.level + 1) % (b.maxLevel + 1)
+	case "set":
+		if len(args) < 2 {
+			return errors.New("usage: keyboard set <level>")
+		}
+
+		var err error
+		level, err = strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+		if level < 0 || level > b.maxLevel {
+			return fmt.Errorf("level must be between 0 and %d", b.maxLevel)
+		}
+	default:
+		return fmt.Errorf("unknown keyboard command: %s", args[0])
+	}
+
+	if err := setBrightness("leds", b.device, level); err != nil {
+		return err
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Icon returns the keyboard icon
+func (b *KeyboardBlock) Icon() rune {
+	return keyboardIcon
+}
+
+// Text returns the level out of the maximum level, like "2/3"
+func (b *KeyboardBlock) Text() (primary, secondary string) {
+	return fmt.Sprintf("%d/%d", b.level, b.maxLevel), ""
+}
+
+// Colorer returns a pointer to the block's fader, for color
+func (b *KeyboardBlock) Colorer() format.Colorer {
+	return b.fader
+}
+
+// Hidden returns true if there's no keyboard backlight
+func (b *KeyboardBlock) Hidden() bool {
+	return b.device == ""
+}
+
+// ForceShort returns false
+func (b *KeyboardBlock) ForceShort() bool {
+	return false
+}
+
+// Output returns the block, clickable to cycle levels
+func (b *KeyboardBlock) Output(mode format.Mode) string {
+	if b.Hidden() {
+		return ""
+	}
+	return format.Action(keyboardCycleAction, format.FormatClassicBlock(b))
+}
+
+func (b *KeyboardBlock) getDir() string {
+	return ledsDir + b.device
+}
@@ -1,11 +1,25 @@
+// Package daemon serves the status bar over TCP.
+//
+// A client's first line says what it wants. Status clients send "subscribe"
+// and are then sent a line for every change to the status. Anything else is a
+// command, like "volume inc 5" or "query weather", which gets at most one line
+// back before the connection is closed.
+//
+// Clients from before "subscribe" send nothing. They still get the status,
+// but only after commandTimeout (a second) passes without a line, so their
+// first status is that much late; sending "subscribe" avoids the wait.
 package daemon
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os/exec"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/muni-corn/muse-status/format"
 )
@@ -15,9 +29,21 @@ type Daemon struct {
 	leftBlocks   []format.DataBlock
 	centerBlocks []format.DataBlock
 	rightBlocks  []format.DataBlock
-	connections  []net.Conn
+
+	connections     []net.Conn
+	connectionsLock sync.Mutex
 }
 
+// SubscribeCommand is sent by clients that want the status, as soon as they
+// connect. Anything else is a command
+const SubscribeCommand = "subscribe"
+
+// commandTimeout is how long the daemon waits for a new connection to say what
+// it wants. Clients that say nothing in time get the status, for clients that
+// don't send SubscribeCommand; a command that takes longer than this to arrive
+// is mistaken for one of them
+const commandTimeout = time.Second
+
 func New(addr string, leftBlocks, centerBlocks, rightBlocks []format.DataBlock) *Daemon {
 	d := &Daemon{
 		addr:         addr,
@@ -52,7 +78,7 @@ func (d *Daemon) Start() error {
 				continue
 			}
 
-			d.handleConnection(conn, currentStatus)
+			go d.handleConnection(conn, currentStatus)
 		}
 	}()
 
@@ -71,46 +97,88 @@ func (d *Daemon) Start() error {
 	return nil
 }
 
-func (d *Daemon) HandleCommand(cmd string) error {
-	split := strings.SplitN(cmd, " ", 2)
+// HandleCommand handles a command sent to the daemon, returning a response
+// for the client that sent it, if any
+func (d *Daemon) HandleCommand(cmd string) (string, error) {
+	split := strings.Fields(cmd)
+	if len(split) == 0 {
+		return "", fmt.Errorf("no command given")
+	}
 
 	switch split[0] {
 	case "notify":
 		if len(split) < 2 {
 			break
 		}
-		notifyName := split[1][:len(split[1])-1] // trims new line
-		d.notify(notifyName)
+		d.notify(split[1])
+	case "query":
+		if len(split) < 2 {
+			return "", fmt.Errorf("usage: query <block>")
+		}
+		return d.query(split[1])
+	case "details":
+		if len(split) < 2 {
+			return "", fmt.Errorf("usage: details <block>")
+		}
+		return d.details(split[1])
 	default:
-		return fmt.Errorf("unhandled command: %s", split[0])
+		return "", d.commandBlock(split[0], split[1:])
 	}
 
-	return nil
+	return "", nil
 }
 
-func (d *Daemon) handleConnection(conn net.Conn, init string) {
-	d.connections = append(d.connections, conn)
+// commandBlock passes a command to the block named `name`. blocks wake their
+// own broadcasts after commands, which publish the new state; updating them
+// from here would race with that
+func (d *Daemon) commandBlock(name string, args []string) error {
+	for _, b := range d.allBlocks() {
+		if b.Name() != name {
+			continue
+		}
 
-	if format.GetFormatMode() == format.I3JSONMode {
-		conn.Write([]byte(`{"version":1}` + "\n["))
+		c, ok := b.(format.CommandBlock)
+		if !ok {
+			break
+		}
+
+		return c.HandleCommand(args)
 	}
 
-	conn.Write([]byte(init + "\n"))
+	return fmt.Errorf("unhandled command: %s", name)
+}
 
+func (d *Daemon) handleConnection(conn net.Conn, init string) {
 	r := bufio.NewReader(conn)
 
-	go func() {
-		str, err := r.ReadString('\n')
-		if err != nil {
-			return
-		}
+	// clients send a command, or SubscribeCommand for the status, as soon
+	// as they connect. if nothing arrives in time, this is an older client
+	// that wants the status
+	conn.SetReadDeadline(time.Now().Add(commandTimeout))
+	str, err := r.ReadString('\n')
+	conn.SetReadDeadline(time.Time{})
+	if err == nil && strings.TrimSpace(str) != SubscribeCommand {
+		defer conn.Close()
 
 		// try to handle a command
-		err = d.HandleCommand(str)
+		res, err := d.HandleCommand(str)
 		if err != nil {
 			conn.Write([]byte(err.Error() + "\n"))
+		} else if res != "" {
+			conn.Write([]byte(res + "\n"))
 		}
-	}()
+		return
+	}
+
+	if format.GetFormatMode() == format.I3JSONMode {
+		conn.Write([]byte(`{"version":1}` + "\n["))
+	}
+
+	conn.Write([]byte(init + "\n"))
+
+	d.connectionsLock.Lock()
+	d.connections = append(d.connections, conn)
+	d.connectionsLock.Unlock()
 }
 
 func (d *Daemon) allBlocks() []format.DataBlock {
@@ -176,6 +244,9 @@ func (d *Daemon) makeStatusString(isFirst *bool) string {
 }
 
 func (d *Daemon) echo(str string) error {
+	d.connectionsLock.Lock()
+	defer d.connectionsLock.Unlock()
+
 	for _, conn := range d.connections {
 		_, err := conn.Write([]byte(str + "\n"))
 		if err != nil {
@@ -200,6 +271,65 @@ func (d *Daemon) notify(what string) {
 	}
 }
 
+// findQueryable returns the block named `name`, if it can be queried
+func (d *Daemon) findQueryable(name string) (format.QueryableBlock, error) {
+	for _, b := range d.allBlocks() {
+		if b.Name() != name {
+			continue
+		}
+
+		q, ok := b.(format.QueryableBlock)
+		if !ok {
+			return nil, fmt.Errorf("%s can't be queried", name)
+		}
+		return q, nil
+	}
+
+	return nil, fmt.Errorf("no block named %s", name)
+}
+
+// query returns the information of a block as json
+func (d *Daemon) query(name string) (string, error) {
+	q, err := d.findQueryable(name)
+	if err != nil {
+		return "", err
+	}
+
+	j, err := json.Marshal(q.Query())
+	if err != nil {
+		return "", err
+	}
+
+	return string(j), nil
+}
+
+// details returns the information of a block in a human-readable form: the
+// block's own, or one "key: value" line per entry
+func (d *Daemon) details(name string) (string, error) {
+	q, err := d.findQueryable(name)
+	if err != nil {
+		return "", err
+	}
+
+	if detailed, ok := q.(format.DetailedBlock); ok {
+		return detailed.Details(), nil
+	}
+
+	info := q.Query()
+	keys := make([]string, 0, len(info))
+	for k := range info {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = fmt.Sprintf("%s: %v", k, info[k])
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
 func (d *Daemon) listenForXorgChanges() {
 	cmd := exec.Command("bspc", "subscribe", "report")
 	r, err := cmd.StdoutPipe()
@@ -43,6 +43,42 @@ type ClassicBlock interface {
 	Colorer() Colorer
 }
 
+// QueryableBlock can report more about itself than fits in the status bar.
+// The daemon serves this information through the `query` and `details`
+// commands
+type QueryableBlock interface {
+	DataBlock
+
+	Query() map[string]interface{}
+}
+
+// DetailedBlock formats its own `details` output, for information that
+// doesn't read well as a list of keys and values (like a forecast)
+type DetailedBlock interface {
+	QueryableBlock
+
+	Details() string
+}
+
+// CommandBlock can be controlled with commands sent through the daemon. The
+// first word of a command is the name of the block, and the rest are passed
+// as args; e.g. `brightness inc 5`. Commands come from the daemon's
+// connections, not the block's broadcast, so the broadcast should be woken to
+// show the result
+type CommandBlock interface {
+	DataBlock
+
+	HandleCommand(args []string) error
+}
+
+// DaylightBlock knows whether the sun is up, for features like theme switching
+// to follow. ok is false if it doesn't know yet
+type DaylightBlock interface {
+	DataBlock
+
+	Daytime() (day, ok bool)
+}
+
 // BanneringBlock has the ability to display banners in the status bar
 type BanneringBlock interface {
 	Banner(interpolation float32) string
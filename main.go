@@ -1,11 +1,13 @@
 package main
 
 import (
+	"github.com/muni-corn/muse-status/astro"
 	"github.com/muni-corn/muse-status/brightness"
 	// "github.com/muni-corn/muse-status/bspwm"
 	"github.com/muni-corn/muse-status/daemon"
 	"github.com/muni-corn/muse-status/date"
 	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/mic"
 	"github.com/muni-corn/muse-status/network"
 	"github.com/muni-corn/muse-status/playerctl"
 	"github.com/muni-corn/muse-status/sbattery"
@@ -16,13 +18,43 @@ import (
 
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const addr = ":1612"
 
+var (
+	batterySecondaryMode = sbattery.TimeRemainingMode
+	peripheralWarning    = 20
+	peripheralAlarm      = 10
+	volumeStep           = 5
+	volumeMax            = 100
+
+	networkInterface     string // empty to follow the default route
+	connectivityURL      string
+	connectivityResponse string
+
+	weatherProvider  = "open-meteo"
+	weatherKey       string
+	weatherUnits     = weather.Imperial
+	weatherPrimary   = []string{"temperature"}
+	weatherSecondary = []string{"description"}
+	weatherLocation  *weather.WeatherLocation // nil to find it
+	weatherCity      string
+	weatherIPLookup  bool
+	weatherAlerts    string // empty or "none" for no alerts
+
+	astroLocation []float64 // latitude and longitude; the weather's if unset
+
+	networkSecondaryMode = network.StatusMode
+	throughputWindow     time.Duration
+)
+
 func main() {
 	handleArgs()
 
@@ -31,23 +63,76 @@ func main() {
 	batteryBlock, err := sbattery.NewSmartBatteryBlock("BAT0", 30, 15)
 	if err != nil {
 		// println(err)
+	} else {
+		batteryBlock.SetSecondaryMode(batterySecondaryMode)
 	}
 
-	brightnessBlock, err := brightness.NewBrightnessBlock("amdgpu_bl0", false)
+	peripheralBlock := sbattery.NewPeripheralBlock(peripheralWarning, peripheralAlarm)
+
+	brightnessBlock, err := brightness.NewBrightnessBlock("", false)
 	if err != nil {
 		// println(err)
 	}
 
+	keyboardBlock := brightness.NewKeyboardBlock()
+
 	dateBlock := date.NewDateBlock()
-	networkBlock, err := network.NewNetworkBlock("wlo1")
+	networkBlock, err := network.NewNetworkBlock(networkInterface)
 	if err != nil {
 		// println(err)
+	} else {
+		if connectivityURL != "" {
+			networkBlock.SetConnectivityEndpoint(connectivityURL, connectivityResponse)
+		}
+		networkBlock.SetSecondaryMode(networkSecondaryMode)
+		networkBlock.SetThroughputWindow(throughputWindow)
 	}
 
+	vpnBlock := network.NewVPNBlock()
+
 	playerctlBlock := playerctl.NewPlayerctlBlock()
 	volumeBlock := volume.NewVolumeBlock(false)
+	volumeBlock.SetStep(volumeStep)
+	volumeBlock.SetMaxVolume(volumeMax)
+	micBlock := mic.NewMicBlock()
 	// windowBlock := window.NewWindowBlock(false)
-	weatherBlock := weather.NewWeatherBlock(nil)
+	weatherBlock := weather.NewWeatherBlock(weatherLocation)
+	weatherBlock.SetCity(weatherCity)
+	weatherBlock.SetIPLookup(weatherIPLookup)
+	if provider, err := weather.NewProvider(weatherProvider, weatherKey); err == nil {
+		weatherBlock.SetProvider(provider)
+	} else {
+		weatherBlock.SetProviderError(err)
+	}
+	if weatherAlerts != "" && weatherAlerts != "none" {
+		if provider, err := weather.NewAlertProvider(weatherAlerts, weatherKey); err == nil {
+			weatherBlock.SetAlertProvider(provider)
+		} else {
+			fmt.Fprintln(os.Stderr, "weather:", err)
+		}
+	}
+	weatherBlock.SetUnits(weatherUnits)
+	if networkBlock != nil {
+		weatherBlock.RefreshOn(networkBlock.Reconnected())
+	}
+	if err := weatherBlock.SetFields(weatherPrimary, weatherSecondary); err != nil {
+		fmt.Fprintln(os.Stderr, "weather:", err)
+	}
+
+	// the astro block works offline, so it needs to be told where it is
+	var astroBlock format.DataBlock
+	if astroLocation == nil && weatherLocation != nil {
+		astroLocation = []float64{float64(weatherLocation.Latitude), float64(weatherLocation.Longitude)}
+	}
+	if astroLocation != nil {
+		if b, err := astro.NewAstroBlock(astroLocation[0], astroLocation[1]); err == nil {
+			astroBlock = b
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "astro: no location, so the block is hidden. set --astro-location or --weather-location")
+	}
 
 	var (
 		leftBlocks   []format.DataBlock
@@ -61,13 +146,13 @@ func main() {
 // 		}
 // 	}
 
-	for _, b := range []format.DataBlock{dateBlock, weatherBlock, playerctlBlock} {
+	for _, b := range []format.DataBlock{dateBlock, weatherBlock, astroBlock, playerctlBlock} {
 		if b != nil {
 			centerBlocks = append(centerBlocks, b)
 		}
 	}
 
-	for _, b := range []format.DataBlock{brightnessBlock, volumeBlock, networkBlock, batteryBlock} {
+	for _, b := range []format.DataBlock{brightnessBlock, keyboardBlock, micBlock, volumeBlock, vpnBlock, networkBlock, peripheralBlock, batteryBlock} {
 		if b != nil {
 			rightBlocks = append(rightBlocks, b)
 		}
@@ -100,6 +185,10 @@ func main() {
 }
 
 func handleClient(conn net.Conn) error {
+	if _, err := conn.Write([]byte(daemon.SubscribeCommand + "\n")); err != nil {
+		panic(err)
+	}
+
 	r := bufio.NewReader(conn)
 	for {
 		str, err := r.ReadString('\n')
@@ -145,10 +234,100 @@ func handleArgs() {
             case "lemon":
                 format.SetFormatMode(format.LemonbarMode)
             }
+		case "--battery-secondary":
+			switch next {
+			case "time":
+				batterySecondaryMode = sbattery.TimeRemainingMode
+			case "power":
+				batterySecondaryMode = sbattery.PowerDrawMode
+			}
+		case "--peripheral-warning":
+			if n, err := strconv.Atoi(next); err == nil {
+				peripheralWarning = n
+			}
+		case "--peripheral-alarm":
+			if n, err := strconv.Atoi(next); err == nil {
+				peripheralAlarm = n
+			}
+		case "--volume-step":
+			if n, err := strconv.Atoi(next); err == nil {
+				volumeStep = n
+			}
+		case "--volume-max":
+			if n, err := strconv.Atoi(next); err == nil {
+				volumeMax = n
+			}
+		case "--weather-provider":
+			weatherProvider = next
+		case "--weather-key":
+			weatherKey = next
+		case "--weather-units":
+			switch next {
+			case "imperial":
+				weatherUnits = weather.Imperial
+			case "metric":
+				weatherUnits = weather.Metric
+			case "standard":
+				weatherUnits = weather.Standard
+			}
+		case "--weather-location":
+			if coords, ok := parseCoordinates(next); ok {
+				weatherLocation = &weather.WeatherLocation{Latitude: float32(coords[0]), Longitude: float32(coords[1])}
+			}
+		case "--astro-location":
+			if coords, ok := parseCoordinates(next); ok {
+				astroLocation = coords
+			} else {
+				fmt.Fprintln(os.Stderr, "astro: invalid location:", next)
+			}
+		case "--weather-city":
+			weatherCity = next
+		case "--weather-ip-lookup":
+			if allowed, err := strconv.ParseBool(next); err == nil {
+				weatherIPLookup = allowed
+			}
+		case "--weather-alerts":
+			weatherAlerts = next
+		case "--weather-primary":
+			weatherPrimary = strings.Split(next, ",")
+		case "--weather-secondary":
+			weatherSecondary = strings.Split(next, ",")
+		case "--network-interface":
+			networkInterface = next
+		case "--connectivity-url":
+			connectivityURL = next
+		case "--connectivity-response":
+			connectivityResponse = next
+		case "--network-secondary":
+			switch next {
+			case "status":
+				networkSecondaryMode = network.StatusMode
+			case "throughput":
+				networkSecondaryMode = network.ThroughputMode
+			}
+		case "--throughput-window":
+			if n, err := strconv.Atoi(next); err == nil {
+				throughputWindow = time.Duration(n) * time.Second
+			}
 		}
 	}
 }
 
+// parseCoordinates parses "latitude,longitude"
+func parseCoordinates(s string) ([]float64, bool) {
+	coords := strings.Split(s, ",")
+	if len(coords) != 2 {
+		return nil, false
+	}
+
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+	if latErr != nil || lonErr != nil {
+		return nil, false
+	}
+	return []float64{lat, lon}, true
+}
+
 func sendCommand(args []string) error {
 	str := strings.Join(args, " ")
 
@@ -157,11 +336,20 @@ func sendCommand(args []string) error {
 		return err
 	}
 
+	defer conn.Close()
+
 	_, err = conn.Write([]byte(str + "\n"))
 	if err != nil {
 		panic(err)
 	}
 
+	// print whatever the daemon has to say in response
+	res, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(res))
+
 	return nil
 }
 
@@ -0,0 +1,211 @@
+package mic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/pulse"
+)
+
+// reconnectInterval is how often we try to reach the PulseAudio server after
+// losing it
+const reconnectInterval = time.Second * 5
+
+// Block shows whether the microphone is live. It's hidden unless something
+// is recording, and alarm-colored while recording unmuted
+type Block struct {
+	mutex  sync.Mutex    // guards client, which commands use too
+	client *pulse.Client // nil if no server is available
+	events <-chan pulse.Event
+
+	source    pulse.Device // the default source
+	recording []string     // names of applications that are recording
+}
+
+// NewMicBlock returns a new mic.Block
+func NewMicBlock() *Block {
+	b := &Block{}
+	b.connect()
+	return b
+}
+
+func (b *Block) connect() {
+	client, err := pulse.Dial("muse-status")
+	if err != nil {
+		return
+	}
+
+	events, err := client.Subscribe(pulse.SubscribeSource | pulse.SubscribeSourceOutput | pulse.SubscribeServer)
+	if err != nil {
+		client.Close()
+		return
+	}
+
+	b.events = events
+	b.setClient(client)
+}
+
+// currentClient returns the PulseAudio client, or nil if there is no server.
+// Only the broadcast changes it, but commands come from other goroutines, so
+// they should keep the pointer they get from here
+func (b *Block) currentClient() *pulse.Client {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.client
+}
+
+func (b *Block) setClient(client *pulse.Client) {
+	b.mutex.Lock()
+	b.client = client
+	b.mutex.Unlock()
+}
+
+// StartBroadcast starts broadcasting from this block. It returns a channel
+// that sends output when an update should happen
+func (b *Block) StartBroadcast() <-chan bool {
+	c := make(chan bool)
+	go b.broadcast(c)
+	return c
+}
+
+func (b *Block) broadcast(c chan<- bool) {
+	for {
+		b.Update()
+		c <- true
+
+		// a nil events channel blocks forever, leaving only the timer
+		var reconnect <-chan time.Time
+		if b.client == nil {
+			reconnect = time.After(reconnectInterval)
+		}
+
+		select {
+		case _, ok := <-b.events:
+			if !ok {
+				b.client.Close()
+				b.setClient(nil)
+				b.events = nil
+			}
+		case <-reconnect:
+			b.connect()
+		}
+	}
+}
+
+// Update reads the default source and any recording streams
+func (b *Block) Update() {
+	client := b.currentClient()
+	if client == nil {
+		b.source, b.recording = pulse.Device{}, nil
+		return
+	}
+
+	source, err := client.Source(pulse.DefaultSource)
+	if err != nil {
+		return
+	}
+
+	recording, err := getRecordingApps(client)
+	if err != nil {
+		return
+	}
+
+	b.source, b.recording = source, recording
+}
+
+// HandleCommand handles `toggle`, `mute` and `unmute` for the default source
+func (b *Block) HandleCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: mic toggle|mute|unmute")
+	}
+	client := b.currentClient()
+	if client == nil {
+		return errors.New("no PulseAudio server available")
+	}
+
+	var mute bool
+	switch args[0] {
+	case "toggle":
+		source, err := client.Source(pulse.DefaultSource)
+		if err != nil {
+			return err
+		}
+		mute = !source.Mute
+	case "mute":
+		mute = true
+	case "unmute":
+		mute = false
+	default:
+		return fmt.Errorf("unknown mic command: %s", args[0])
+	}
+
+	return client.SetSourceMute(pulse.DefaultSource, mute)
+}
+
+// Name returns "mic"
+func (b *Block) Name() string {
+	return "mic"
+}
+
+// Icon returns a microphone icon, crossed out if muted
+func (b *Block) Icon() rune {
+	if b.source.Mute {
+		return micMutedIcon
+	}
+	return micIcon
+}
+
+// Text returns the volume (or "Muted") as primary, and the applications
+// recording as secondary
+func (b *Block) Text() (primary, secondary string) {
+	if b.source.Mute {
+		primary = "Muted"
+	} else {
+		primary = fmt.Sprintf("%d%%", b.source.VolumePercent())
+	}
+
+	return primary, strings.Join(b.recording, ", ")
+}
+
+// Colorer returns the alarm colorer while the mic is live
+func (b *Block) Colorer() format.Colorer {
+	if b.live() {
+		return format.GetAlarmColorer()
+	}
+	return format.GetDimColorer()
+}
+
+// Hidden returns true if nothing is recording
+func (b *Block) Hidden() bool {
+	return len(b.recording) == 0
+}
+
+// ForceShort returns false
+func (b *Block) ForceShort() bool {
+	return false
+}
+
+func (b *Block) Output(mode format.Mode) string {
+	return format.FormatClassicBlock(b)
+}
+
+// Query returns the default source and the applications recording from it
+func (b *Block) Query() map[string]interface{} {
+	return map[string]interface{}{
+		"source":      b.source.Name,
+		"description": b.source.Description,
+		"volume":      b.source.VolumePercent(),
+		"muted":       b.source.Mute,
+		"recording":   b.recording,
+		"live":        b.live(),
+	}
+}
+
+// live returns true if something is recording from an unmuted mic
+func (b *Block) live() bool {
+	return len(b.recording) > 0 && !b.source.Mute
+}
@@ -0,0 +1,60 @@
+package mic
+
+import (
+	"sort"
+
+	"github.com/muni-corn/muse-status/pulse"
+)
+
+const (
+	micIcon      = ''
+	micMutedIcon = ''
+)
+
+// streams from these applications only watch levels; they aren't recording
+var ignoredApplications = map[string]bool{
+	"org.PulseAudio.pavucontrol": true,
+	"org.pipewire.Helvum":        true,
+}
+
+// getRecordingApps returns the names of applications recording from real
+// inputs (not monitors of outputs)
+func getRecordingApps(client *pulse.Client) ([]string, error) {
+	outputs, err := client.SourceOutputs()
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+
+	sources, err := client.Sources()
+	if err != nil {
+		return nil, err
+	}
+
+	monitors := make(map[uint32]bool)
+	for _, s := range sources {
+		monitors[s.Index] = s.Monitor
+	}
+
+	seen := make(map[string]bool)
+	var apps []string
+	for _, o := range outputs {
+		if o.Corked || monitors[o.Source] || ignoredApplications[o.Props["application.id"]] {
+			continue
+		}
+
+		name := o.Props["application.name"]
+		if name == "" {
+			name = o.Name
+		}
+		if !seen[name] {
+			seen[name] = true
+			apps = append(apps, name)
+		}
+	}
+
+	sort.Strings(apps)
+	return apps, nil
+}
@@ -5,47 +5,98 @@ import (
 	"github.com/mdlayher/wifi"
 
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 )
 
-// Block is a block that transmits time and date data
+// Block is a block that shows the network connection. Unless it's given an
+// interface, it follows the default route; otherwise a wired connection is
+// preferred over Wi-Fi when both are up
 type Block struct {
-	iface  *wifi.Interface
-	client *wifi.Client
-
+	interfaceName string          // fixed interface, or empty to follow the default route
+	route         string          // interface of the default route
+	iface         *wifi.Interface // the Wi-Fi interface, if there is one
+	client        *wifi.Client    // nil if nl80211 isn't available
+
+	connectivity  *connectivityChecker
+	online        bool          // whether the Internet was reachable at the last update
+	reconnected   chan struct{} // notified when the Internet becomes reachable
+	throughput    throughput
+	secondaryMode SecondaryMode
+
+	currentKind        connectionKind
 	currentSSID        string
 	currentStrengthPct int
 	currentStatus      networkStatus
+	currentWired       wiredLink
+	currentRfkill      rfkillState
 
 	dBm int
 
+	lastKind        connectionKind
 	lastSSID        string
 	lastStrengthPct int
 	lastStatus      networkStatus
+	lastWired       wiredLink
+	lastRfkill      rfkillState
+	lastThroughput  string
 }
 
-// NewDateBlock returns a new date.Block
+// connectionKind is the kind of connection the block is showing
+type connectionKind int
+
+// Definitions for connectionKind
+const (
+	wifiConnection connectionKind = iota
+	wiredConnection
+)
+
+// NewNetworkBlock returns a new network.Block. If interfaceName is empty, the
+// block shows whichever interface the default route goes through. Otherwise
+// it's the Wi-Fi interface to watch, and wired interfaces are found on their
+// own
 func NewNetworkBlock(interfaceName string) (*Block, error) {
+	if interfaceName != "" {
+		if _, err := os.Stat(filepath.Join(sysClassNet, interfaceName)); err != nil {
+			return nil, errors.New("no interface found for " + interfaceName)
+		}
+	}
+
+	// a machine without nl80211 just doesn't get Wi-Fi information
 	client, err := wifi.New()
 	if err != nil {
-		return nil, err
+		client = nil
+	}
+
+	return &Block{
+		interfaceName: interfaceName,
+		client:        client,
+		connectivity:  newConnectivityChecker(),
+		reconnected:   make(chan struct{}, 1),
+	}, nil
+}
+
+// setWifiInterface selects the Wi-Fi interface to show, by name
+func (b *Block) setWifiInterface(name string) {
+	if b.iface != nil && b.iface.Name == name {
+		return
+	}
+
+	b.iface = nil
+	if b.client == nil || name == "" || !isWireless(name) {
+		return
 	}
 
 	// get all interfaces
-	ifs, err := client.Interfaces()
+	ifs, err := b.client.Interfaces()
 	if err != nil {
-		return nil, err
-	}
-	iface := getInterface(interfaceName, ifs)
-	if iface == nil {
-		return nil, errors.New("no interface found for " + interfaceName)
+		return
 	}
 
 	// but only select the one we want
-	return &Block{
-		client: client,
-		iface:  iface,
-	}, nil
+	b.iface = getInterface(name, ifs)
 }
 
 // only returns one Interface that matches the name given
@@ -59,6 +110,24 @@ func getInterface(interfaceName string, interfaces []*wifi.Interface) *wifi.Inte
 	return nil
 }
 
+// SetConnectivityEndpoint sets the URL probed to check for an Internet
+// connection, and the body it answers with. If response is empty, the URL
+// should answer with 204 No Content instead
+func (b *Block) SetConnectivityEndpoint(url, response string) {
+	b.connectivity.setEndpoint(url, response)
+}
+
+// SetSecondaryMode sets what the block shows as secondary text while
+// connected
+func (b *Block) SetSecondaryMode(mode SecondaryMode) {
+	b.secondaryMode = mode
+}
+
+// SetThroughputWindow sets how long throughput rates are averaged over
+func (b *Block) SetThroughputWindow(window time.Duration) {
+	b.throughput.setWindow(window)
+}
+
 func (b *Block) StartBroadcast() <-chan bool {
 	c := make(chan bool)
 	go b.broadcast(c)
@@ -66,18 +135,65 @@ func (b *Block) StartBroadcast() <-chan bool {
 }
 
 func (b *Block) broadcast(c chan<- bool) {
+	events := watchNetwork()
+	b.Update()
+
+	var poll, rates <-chan time.Time
 	for {
-		b.Update()
+		b.throughput.sample(b.currentInterface())
+		b.checkReconnected()
 		if b.shouldNotify() {
 			c <- true
 		}
 
-		time.Sleep(time.Second * 5)
+		// connections come and go with events, so we only poll for signal
+		// strength. without events, we poll for everything
+		if poll == nil && (events == nil || (b.currentKind == wifiConnection && b.currentStatus != disconnectedStatus)) {
+			poll = time.After(time.Second * updateIntervalSeconds)
+		}
+		if rates == nil && b.secondaryMode == ThroughputMode && b.currentStatus != disconnectedStatus {
+			rates = time.After(throughputInterval)
+		}
+
+		select {
+		case <-events:
+			b.Update()
+		case <-b.connectivity.changes:
+			b.Update()
+		case <-poll:
+			poll = nil
+			b.Update()
+		case <-rates:
+			rates = nil
+		}
+	}
+}
+
+// Reconnected returns a channel that is notified when the Internet becomes
+// reachable, so that other blocks can refresh right away
+func (b *Block) Reconnected() <-chan struct{} {
+	return b.reconnected
+}
+
+func (b *Block) checkReconnected() {
+	online := b.currentStatus == connectedStatus || b.currentStatus == slowStatus || b.currentStatus == weakStatus
+	if online && !b.online {
+		notify(b.reconnected)
 	}
+	b.online = online
 }
 
 func (b *Block) shouldNotify() bool {
-	if b.lastSSID != b.currentSSID || b.lastStatus != b.currentStatus || b.lastStrengthPct != b.currentStrengthPct {
+	var throughput string
+	if b.secondaryMode == ThroughputMode {
+		throughput = b.throughput.text()
+	}
+
+	if b.lastKind != b.currentKind || b.lastWired != b.currentWired || b.lastSSID != b.currentSSID || b.lastStatus != b.currentStatus || b.lastStrengthPct != b.currentStrengthPct || b.lastRfkill != b.currentRfkill || b.lastThroughput != throughput {
+		b.lastThroughput = throughput
+		b.lastRfkill = b.currentRfkill
+		b.lastKind = b.currentKind
+		b.lastWired = b.currentWired
 		b.lastSSID = b.currentSSID
 		b.lastStatus = b.currentStatus
 		b.lastStrengthPct = b.currentStrengthPct
@@ -86,8 +202,92 @@ func (b *Block) shouldNotify() bool {
 	return false
 }
 
+// currentInterface returns the name of the interface we're connected
+// through, or an empty string if disconnected
+func (b *Block) currentInterface() string {
+	switch {
+	case b.currentStatus == disconnectedStatus, b.currentStatus == airplaneStatus:
+		return ""
+	case b.currentKind == wiredConnection:
+		return b.currentWired.name
+	case b.iface != nil:
+		return b.iface.Name
+	default:
+		return ""
+	}
+}
+
 // Update updates the network information
 func (b *Block) Update() {
+	b.route = defaultRouteInterface()
+	b.currentRfkill = getWifiRfkill()
+
+	if link, ok := b.selectWired(); ok {
+		b.currentKind = wiredConnection
+		b.currentWired = link
+		b.currentSSID = ""
+		b.currentStrengthPct = 0
+		b.currentStatus = b.connectionStatus(link.name)
+		return
+	}
+
+	b.currentKind = wifiConnection
+	b.currentWired = wiredLink{}
+	if b.currentRfkill.blocked() {
+		b.currentSSID = ""
+		b.currentStrengthPct = 0
+		b.currentStatus = airplaneStatus
+	} else {
+		b.setWifiInterface(b.selectWifi())
+		b.updateWifi()
+	}
+
+	if b.currentStatus == disconnectedStatus || b.currentStatus == airplaneStatus {
+		b.connectivity.setInterface("")
+	}
+}
+
+// selectWired returns the wired connection to show, if there is one. the
+// default route decides when it goes through a wired or Wi-Fi interface;
+// otherwise any wired interface that's up is used
+func (b *Block) selectWired() (wiredLink, bool) {
+	if b.interfaceName == "" && b.route != "" {
+		if isWired(b.route) {
+			link := getWiredLink(b.route)
+			return link, link.up
+		}
+		if isWireless(b.route) {
+			return wiredLink{}, false
+		}
+	}
+
+	return getWiredConnection()
+}
+
+// selectWifi returns the name of the Wi-Fi interface to show
+func (b *Block) selectWifi() string {
+	if b.interfaceName != "" {
+		return b.interfaceName
+	}
+	if isWireless(b.route) {
+		return b.route
+	}
+
+	// without a route through Wi-Fi, show the first interface so that we
+	// still see it connecting
+	if ifaces := getWirelessInterfaces(); len(ifaces) > 0 {
+		return ifaces[0]
+	}
+	return ""
+}
+
+// updateWifi updates Wi-Fi information
+func (b *Block) updateWifi() {
+	if b.client == nil || b.iface == nil {
+		b.currentStatus = disconnectedStatus
+		return
+	}
+
 	// strength
 	infos, err := b.client.StationInfo(b.iface)
 	if err != nil {
@@ -111,12 +311,39 @@ func (b *Block) Update() {
 		return
 	}
 
-	if packetLoss(b.iface.Name) {
-		b.currentStatus = packetLossStatus
-	} else {
-		b.currentStatus = connectedStatus
-	}
 	b.currentSSID = bss.SSID
+	b.currentStatus = b.connectionStatus(b.iface.Name)
+	if b.currentStatus == connectedStatus && b.currentStrengthPct < weakSignalPct {
+		b.currentStatus = weakStatus
+	}
+}
+
+// connectionStatus returns the status of a connection through iface, from
+// what the connectivity checker last found
+func (b *Block) connectionStatus(iface string) networkStatus {
+	b.connectivity.setInterface(iface)
+
+	state, _ := b.connectivity.get()
+	switch state {
+	case connectivityFull:
+		return connectedStatus
+	case connectivitySlow:
+		return slowStatus
+	case connectivityPortal:
+		return signInRequired
+	case connectivityUnverified:
+		// the link works, even if the endpoint doesn't
+		return connectedStatus
+	case connectivityNone:
+		return packetLossStatus
+	default:
+		return connectingStatus
+	}
+}
+
+// offline returns true if the Internet can't be used through the connection
+func offline(status networkStatus) bool {
+	return status == packetLossStatus || status == signInRequired
 }
 
 const (
@@ -136,6 +363,41 @@ func dBmToPercentage(dbm float32) float32 {
 	return -0.04*float32(dbm+30)*float32(dbm+30) + 100.0
 }
 
+// HandleCommand handles `wifi on|off|toggle`, which sets the soft block on
+// every Wi-Fi radio
+func (b *Block) HandleCommand(args []string) error {
+	if len(args) < 1 || args[0] != "wifi" {
+		return errors.New("usage: network wifi on|off|toggle")
+	}
+
+	action := "toggle"
+	if len(args) > 1 {
+		action = args[1]
+	}
+
+	state := getWifiRfkill()
+	if !state.present {
+		return errors.New("no Wi-Fi radios found")
+	}
+
+	switch action {
+	case "on":
+		if state.hard {
+			return errors.New("Wi-Fi is turned off by a hardware switch")
+		}
+		return setWifiBlocked(false)
+	case "off":
+		return setWifiBlocked(true)
+	case "toggle":
+		if state.hard {
+			return errors.New("Wi-Fi is turned off by a hardware switch")
+		}
+		return setWifiBlocked(!state.soft)
+	default:
+		return fmt.Errorf("unknown wifi command: %s", action)
+	}
+}
+
 // Name returns "network"
 func (b *Block) Name() string {
 	return "network"
@@ -143,12 +405,114 @@ func (b *Block) Name() string {
 
 // Icon returns the network icon
 func (b *Block) Icon() rune {
+	if b.currentKind == wiredConnection {
+		if offline(b.currentStatus) {
+			return wiredPacketLossIcon
+		}
+		return wiredIcon
+	}
+	if b.currentStatus == airplaneStatus {
+		return disabledIcon
+	}
 	return getIcon(b.currentStrengthPct, b.currentStatus)
 }
 
-// Text returns the ssid as primary, the status as secondary
+// Text returns the ssid (or "Ethernet") as primary, the status as secondary.
+// if nothing is wrong, the secondary text is the throughput in
+// ThroughputMode, or the speed of wired connections
 func (b *Block) Text() (primary, secondary string) {
-	return b.currentSSID, string(b.currentStatus)
+	primary, secondary = b.currentSSID, string(b.currentStatus)
+	if b.currentKind == wiredConnection {
+		primary = "Ethernet"
+	}
+
+	if b.currentStatus == airplaneStatus {
+		// airplaneStatus is shown for any blocked Wi-Fi, but it's only
+		// airplane mode if the other radios are off too
+		primary, secondary = string(airplaneStatus), ""
+		if !b.currentRfkill.airplane {
+			primary = "Wi-Fi off"
+		}
+		if b.currentRfkill.hard {
+			secondary = "Turned off by hardware switch"
+		}
+		return
+	}
+
+	if b.currentStatus != connectedStatus {
+		return
+	}
+
+	if b.secondaryMode == ThroughputMode {
+		secondary = b.throughput.text()
+	} else if b.currentKind == wiredConnection {
+		secondary = formatSpeed(b.currentWired.speed)
+	}
+	return
+}
+
+// Query returns the connection the block is showing
+func (b *Block) Query() map[string]interface{} {
+	// blocked Wi-Fi isn't connected either
+	connected := b.currentInterface() != ""
+	info := map[string]interface{}{
+		"status":    string(b.currentStatus),
+		"connected": connected,
+	}
+
+	if connected {
+		if _, latency := b.connectivity.get(); latency > 0 {
+			info["latency_ms"] = latency.Milliseconds()
+		}
+
+		// rates are only sampled steadily when they're shown. otherwise
+		// they'd be averaged over however long it's been since an event
+		if b.secondaryMode == ThroughputMode {
+			rx, tx := b.throughput.rates()
+			info["rx_bytes_per_second"] = int64(rx)
+			info["tx_bytes_per_second"] = int64(tx)
+		}
+		sessionRx, sessionTx := b.throughput.session()
+		info["rx_bytes_session"] = sessionRx
+		info["tx_bytes_session"] = sessionTx
+	}
+
+	switch {
+	case b.currentRfkill.hard:
+		info["wifi_blocked"] = "hard"
+	case b.currentRfkill.soft:
+		info["wifi_blocked"] = "soft"
+	}
+	if b.currentRfkill.blocked() {
+		info["airplane_mode"] = b.currentRfkill.airplane
+	}
+
+	if b.interfaceName == "" {
+		info["selection"] = "default route"
+		info["route"] = b.route
+	} else {
+		info["selection"] = "fixed"
+	}
+
+	if b.currentKind == wiredConnection {
+		info["type"] = "wired"
+		info["interface"] = b.currentWired.name
+		if b.currentWired.speed > 0 {
+			info["speed_mbps"] = b.currentWired.speed
+		}
+		return info
+	}
+
+	info["type"] = "wifi"
+	if b.iface != nil {
+		info["interface"] = b.iface.Name
+	}
+	if b.currentStatus != disconnectedStatus {
+		info["ssid"] = b.currentSSID
+		info["signal_pct"] = b.currentStrengthPct
+		info["signal_dbm"] = b.dBm
+	}
+	return info
 }
 
 // Colorer returns the default colorer
@@ -174,7 +538,7 @@ func getIcon(signalStrengthPct int, status networkStatus) rune {
 	// determine which icons we'll use based on
 	// packetLoss
 	var icons []rune
-	if status == packetLossStatus {
+	if offline(status) {
 		icons = packetLossIcons
 	} else {
 		icons = connectionIcons
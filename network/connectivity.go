@@ -0,0 +1,219 @@
+package network
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// the endpoint NetworkManager uses by default. it answers with a fixed body,
+// which a captive portal won't reproduce
+const (
+	defaultConnectivityURL      = "http://nmcheck.gnome.org/check_network_status.txt"
+	defaultConnectivityResponse = "NetworkManager is online"
+)
+
+const (
+	probeTimeout        = time.Second * 10
+	onlineProbeInterval = time.Second * 60 // while everything is fine
+	failedProbeInterval = time.Second * 5  // while offline or behind a portal
+	slowLatency         = time.Second      // probes slower than this are "slow"
+	maxProbeBody        = 4096
+)
+
+// connectivity is what the last probe found out about the Internet
+type connectivity int
+
+// Definitions for connectivity
+const (
+	connectivityUnknown connectivity = iota // no probe has finished yet
+	connectivityFull
+	connectivitySlow
+	connectivityPortal     // a captive portal answered instead
+	connectivityUnverified // the endpoint answered with an error, so we can't tell
+	connectivityNone
+)
+
+// connectivityChecker probes an HTTP endpoint in the background to find out
+// whether the Internet is reachable through an interface
+type connectivityChecker struct {
+	url      string
+	response string // expected body. if empty, a 204 is expected instead
+
+	mutex   sync.Mutex
+	iface   string
+	state   connectivity
+	latency time.Duration
+
+	changes chan struct{} // notified when state changes
+	wake    chan struct{} // probes immediately
+}
+
+func newConnectivityChecker() *connectivityChecker {
+	c := &connectivityChecker{
+		url:      defaultConnectivityURL,
+		response: defaultConnectivityResponse,
+		changes:  make(chan struct{}, 1),
+		wake:     make(chan struct{}, 1),
+	}
+	go c.run()
+	return c
+}
+
+// setEndpoint sets the URL to probe and the body it should answer with. if
+// response is empty, the endpoint should answer with 204 No Content
+func (c *connectivityChecker) setEndpoint(url, response string) {
+	c.mutex.Lock()
+	c.url, c.response = url, response
+	c.mutex.Unlock()
+
+	c.probeNow()
+}
+
+// setInterface sets the interface to probe through. changing it forgets
+// what we knew and probes again right away
+func (c *connectivityChecker) setInterface(iface string) {
+	c.mutex.Lock()
+	changed := iface != c.iface
+	if changed {
+		c.iface = iface
+		c.state, c.latency = connectivityUnknown, 0
+	}
+	c.mutex.Unlock()
+
+	if changed {
+		c.probeNow()
+	}
+}
+
+// get returns the result of the last probe
+func (c *connectivityChecker) get() (connectivity, time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.state, c.latency
+}
+
+func (c *connectivityChecker) probeNow() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *connectivityChecker) run() {
+	for {
+		c.mutex.Lock()
+		iface, url, response := c.iface, c.url, c.response
+		c.mutex.Unlock()
+
+		interval := failedProbeInterval
+		if iface != "" {
+			state, latency := probe(newProbeClient(iface, probeTimeout), url, response)
+
+			c.mutex.Lock()
+			// the interface may have changed while we were probing, which
+			// makes this result useless
+			if iface == c.iface && (state != c.state || latency != c.latency) {
+				notify := state != c.state
+				c.state, c.latency = state, latency
+				if notify {
+					select {
+					case c.changes <- struct{}{}:
+					default:
+					}
+				}
+			}
+			c.mutex.Unlock()
+
+			// a broken endpoint won't be fixed by asking it more often
+			if state == connectivityFull || state == connectivitySlow || state == connectivityUnverified {
+				interval = onlineProbeInterval
+			}
+		}
+
+		select {
+		case <-c.wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// newProbeClient returns a client for probing through iface, which doesn't
+// follow redirects
+func newProbeClient(iface string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:       bindDialer(iface, timeout).DialContext,
+			DisableKeepAlives: true,
+			Proxy:             nil,
+		},
+		// portals redirect to their sign-in page; we want to see that
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// probe requests url with client. a redirect, or a successful answer that
+// isn't the expected one, means a captive portal is in the way. errors from
+// the endpoint itself don't tell us anything
+func probe(client *http.Client, url, response string) (connectivity, time.Duration) {
+	start := time.Now()
+	res, err := client.Get(url)
+	if err != nil {
+		return connectivityNone, 0
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxProbeBody))
+	latency := time.Since(start)
+	if err != nil {
+		return connectivityNone, 0
+	}
+
+	switch {
+	case res.StatusCode >= 300 && res.StatusCode < 400:
+		return connectivityPortal, latency
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return connectivityUnverified, latency
+	}
+
+	// portals answer everything with their sign-in page
+	if response == "" {
+		if res.StatusCode != http.StatusNoContent {
+			return connectivityPortal, latency
+		}
+	} else if strings.TrimSpace(string(body)) != response {
+		return connectivityPortal, latency
+	}
+
+	if latency > slowLatency {
+		return connectivitySlow, latency
+	}
+	return connectivityFull, latency
+}
+
+// bindDialer returns a dialer whose connections go out through iface, so a
+// connection on another interface doesn't answer for it. binding may not be
+// permitted on older kernels, in which case the routing table decides. if
+// iface is empty, the routing table always decides
+func bindDialer(iface string, timeout time.Duration) *net.Dialer {
+	if iface == "" {
+		return &net.Dialer{Timeout: timeout}
+	}
+
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			return conn.Control(func(fd uintptr) {
+				syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+			})
+		},
+	}
+}
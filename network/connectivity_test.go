@@ -0,0 +1,98 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testResponse = "NetworkManager is online"
+
+func TestProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testResponse + "\n"))
+	})
+	mux.HandleFunc("/no-content", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://portal.example/login", http.StatusFound)
+	})
+	mux.HandleFunc("/portal", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Please sign in</body></html>"))
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, test := range []struct {
+		path     string
+		response string
+		want     connectivity
+	}{
+		{"/ok", testResponse, connectivityFull},
+		{"/no-content", "", connectivityFull},
+		{"/redirect", testResponse, connectivityPortal},
+		{"/redirect", "", connectivityPortal},
+		{"/portal", testResponse, connectivityPortal},
+		{"/ok", "", connectivityPortal},
+		{"/error", testResponse, connectivityUnverified},
+		{"/unavailable", "", connectivityUnverified},
+		{"/missing", testResponse, connectivityUnverified}, // a failing endpoint, not a portal
+		{"/slow", testResponse, connectivityNone},
+	} {
+		client := newProbeClient("", time.Millisecond*200)
+		if got, _ := probe(client, srv.URL+test.path, test.response); got != test.want {
+			t.Errorf("%s expecting %q: got %v, want %v", test.path, test.response, got, test.want)
+		}
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	if got, _ := probe(newProbeClient("", time.Second), url, testResponse); got != connectivityNone {
+		t.Errorf("got %v, want %v", got, connectivityNone)
+	}
+}
+
+func TestConnectivityChecker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testResponse))
+	}))
+	defer srv.Close()
+
+	c := newConnectivityChecker()
+	c.setEndpoint(srv.URL, testResponse)
+	c.setInterface("lo")
+
+	timeout := time.After(time.Second * 5)
+	for {
+		if state, _ := c.get(); state == connectivityFull {
+			return
+		}
+
+		select {
+		case <-c.changes:
+		case <-timeout:
+			state, _ := c.get()
+			t.Fatalf("state is %v, want %v", state, connectivityFull)
+		}
+	}
+}
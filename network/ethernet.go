@@ -0,0 +1,110 @@
+package network
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/muni-corn/muse-status/utils"
+)
+
+const sysClassNet = "/sys/class/net"
+
+// arphrdEther is the link type of Ethernet (and Wi-Fi) interfaces in
+// /sys/class/net/<iface>/type
+const arphrdEther = 1
+
+var (
+	wiredIcon           = '\uf200'
+	wiredPacketLossIcon = '\uf202'
+)
+
+// wiredLink is the state of a wired interface
+type wiredLink struct {
+	name  string
+	up    bool // operstate is up and a cable is plugged in
+	speed int  // in Mb/s, or -1 if unknown
+}
+
+// isWireless returns true if the interface is a Wi-Fi device
+func isWireless(iface string) bool {
+	_, err := os.Stat(filepath.Join(sysClassNet, iface, "wireless"))
+	return err == nil
+}
+
+// isWired returns true if the interface is a physical Ethernet device.
+// virtual interfaces (bridges, veths, tunnels) don't have a device link
+func isWired(iface string) bool {
+	dir := filepath.Join(sysClassNet, iface)
+	if t, err := utils.GetIntFromFile(filepath.Join(dir, "type")); err != nil || t != arphrdEther {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+		return false
+	}
+	return !isWireless(iface)
+}
+
+// getWiredInterfaces returns the names of every physical Ethernet interface
+func getWiredInterfaces() []string {
+	infos, err := ioutil.ReadDir(sysClassNet)
+	if err != nil {
+		return nil
+	}
+
+	var ifaces []string
+	for _, info := range infos {
+		if isWired(info.Name()) {
+			ifaces = append(ifaces, info.Name())
+		}
+	}
+	return ifaces
+}
+
+// getWiredLink reads the state of a wired interface from sysfs
+func getWiredLink(iface string) wiredLink {
+	dir := filepath.Join(sysClassNet, iface)
+	link := wiredLink{name: iface, speed: -1}
+
+	operstate, err := utils.GetStringFromFile(filepath.Join(dir, "operstate"))
+	if err != nil || operstate != "up" {
+		return link
+	}
+
+	// carrier can't be read while the interface is down
+	carrier, err := utils.GetIntFromFile(filepath.Join(dir, "carrier"))
+	if err != nil || carrier != 1 {
+		return link
+	}
+	link.up = true
+
+	// speed is -1 (or unreadable) if the driver doesn't know it
+	if speed, err := utils.GetIntFromFile(filepath.Join(dir, "speed")); err == nil && speed > 0 {
+		link.speed = speed
+	}
+
+	return link
+}
+
+// getWiredConnection returns the first wired interface that is up
+func getWiredConnection() (wiredLink, bool) {
+	for _, iface := range getWiredInterfaces() {
+		if link := getWiredLink(iface); link.up {
+			return link, true
+		}
+	}
+	return wiredLink{}, false
+}
+
+// formatSpeed returns a link speed in Mb/s as text, like "100 Mb/s" or
+// "2.5 Gb/s"
+func formatSpeed(mbps int) string {
+	if mbps < 0 {
+		return ""
+	}
+	if mbps >= 1000 {
+		return fmt.Sprintf("%g Gb/s", float64(mbps)/1000)
+	}
+	return fmt.Sprintf("%d Mb/s", mbps)
+}
@@ -0,0 +1,126 @@
+package network
+
+import (
+	"errors"
+
+	"github.com/mdlayher/genetlink"
+	"golang.org/x/sys/unix"
+)
+
+// nl80211 multicast group for connection events
+const nl80211MLMEGroup = "mlme"
+
+// watchNetwork returns a channel that is notified when a link, address or
+// route changes, when a Wi-Fi interface connects, disconnects or roams, or
+// when a radio is blocked or unblocked. Bursts of events are coalesced. The
+// channel is nil (and blocks forever) if no source of events could be opened
+func watchNetwork() <-chan struct{} {
+	c := make(chan struct{}, 1)
+
+	links := watchLinks(c) == nil
+	wireless := watchWifi(c) == nil
+	radios := watchRfkill(c) == nil
+	if !links && !wireless && !radios {
+		return nil
+	}
+
+	return c
+}
+
+// notify sends on c without waiting, so that events pile up into one
+func notify(c chan<- struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// watchLinks notifies c of rtnetlink link, address and route events
+func watchLinks(c chan<- struct{}) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE,
+	})
+	if err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer unix.Close(fd)
+
+		buf := make([]byte, 1<<16)
+		for {
+			// we re-read everything on any event, so the messages themselves
+			// don't matter
+			_, _, err := unix.Recvfrom(fd, buf, 0)
+			if err == unix.EINTR || err == unix.ENOBUFS {
+				continue
+			} else if err != nil {
+				return
+			}
+
+			notify(c)
+		}
+	}()
+
+	return nil
+}
+
+// watchWifi notifies c of nl80211 connect, disconnect and roam events
+func watchWifi(c chan<- struct{}) error {
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return err
+	}
+
+	family, err := conn.GetFamily("nl80211")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	joined := false
+	for _, g := range family.Groups {
+		if g.Name == nl80211MLMEGroup {
+			if err := conn.JoinGroup(g.ID); err != nil {
+				conn.Close()
+				return err
+			}
+			joined = true
+		}
+	}
+	if !joined {
+		conn.Close()
+		return unix.ENOENT
+	}
+
+	go func() {
+		defer conn.Close()
+
+		for {
+			msgs, _, err := conn.Receive()
+			if errors.Is(err, unix.ENOBUFS) {
+				// we missed some, so something probably happened
+				notify(c)
+				continue
+			} else if err != nil {
+				return
+			}
+
+			for _, m := range msgs {
+				switch m.Header.Command {
+				case unix.NL80211_CMD_CONNECT, unix.NL80211_CMD_DISCONNECT, unix.NL80211_CMD_ROAM:
+					notify(c)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -2,7 +2,6 @@ package network
 
 import (
 	// "github.com/muni-corn/muse-status/format"
-	"os/exec"
 	"regexp"
 	// "strconv"
 	// "strings"
@@ -22,7 +21,8 @@ const (
 )
 
 const (
-	updateIntervalSeconds = 5 // interval to update network information, in seconds
+	updateIntervalSeconds = 5  // interval to update network information, in seconds
+	weakSignalPct         = 25 // signal strengths below this are "weak"
 )
 
 var (
@@ -119,9 +119,3 @@ var lineReturnRegex = regexp.MustCompile(`\r?\n`)
 // func getEthernet() string {
 // 	return ""
 // }
-
-func packetLoss(wirelessInterface string) bool {
-	cmd := exec.Command("ping", "-c", "2", "-W", "2", "-I", wirelessInterface, "8.8.8.8")
-	err := cmd.Run()
-	return err != nil
-}
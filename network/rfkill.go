@@ -0,0 +1,119 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/muni-corn/muse-status/utils"
+)
+
+const (
+	sysClassRfkill = "/sys/class/rfkill"
+	devRfkill      = "/dev/rfkill"
+
+	// from linux/rfkill.h
+	rfkillTypeWLAN    = 1
+	rfkillOpChangeAll = 3
+	rfkillEventSize   = 8 // idx (u32), type, op, soft, hard (u8 each)
+)
+
+// rfkillState is whether the Wi-Fi radios are blocked. A soft block is set
+// by software (like airplane mode in a desktop); a hard block is a hardware
+// switch that software can't undo
+type rfkillState struct {
+	present  bool // whether there are any Wi-Fi radios at all
+	soft     bool
+	hard     bool
+	airplane bool // Wi-Fi is blocked along with every other kind of radio
+}
+
+// blocked returns true if every Wi-Fi radio is off
+func (s rfkillState) blocked() bool {
+	return s.present && (s.soft || s.hard)
+}
+
+// getWifiRfkill reads the state of the Wi-Fi radios from sysfs. Radios count
+// as blocked only if all of them are, so one working card is enough. If
+// there are other radios (like Bluetooth) and they're all blocked too, it's
+// airplane mode
+func getWifiRfkill() (state rfkillState) {
+	infos, err := ioutil.ReadDir(sysClassRfkill)
+	if err != nil {
+		return
+	}
+
+	var wifiOn, otherRadios, otherOn bool
+	for _, info := range infos {
+		dir := filepath.Join(sysClassRfkill, info.Name())
+		t, err := utils.GetStringFromFile(filepath.Join(dir, "type"))
+		if err != nil {
+			continue
+		}
+
+		soft, _ := utils.GetIntFromFile(filepath.Join(dir, "soft"))
+		hard, _ := utils.GetIntFromFile(filepath.Join(dir, "hard"))
+		on := soft == 0 && hard == 0
+
+		if t != "wlan" {
+			otherRadios = true
+			otherOn = otherOn || on
+			continue
+		}
+
+		state.present = true
+		wifiOn = wifiOn || on
+		state.soft = state.soft || soft == 1
+		state.hard = state.hard || hard == 1
+	}
+
+	if wifiOn {
+		return rfkillState{present: true}
+	}
+	state.airplane = state.present && otherRadios && !otherOn
+	return state
+}
+
+// setWifiBlocked sets or clears the soft block on every Wi-Fi radio through
+// /dev/rfkill, which is usually writable by the logged-in user
+func setWifiBlocked(blocked bool) error {
+	f, err := os.OpenFile(devRfkill, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	event := make([]byte, rfkillEventSize)
+	event[4] = rfkillTypeWLAN
+	event[5] = rfkillOpChangeAll
+	if blocked {
+		event[6] = 1
+	}
+
+	_, err = f.Write(event)
+	return err
+}
+
+// watchRfkill notifies c when any radio is blocked or unblocked
+func watchRfkill(c chan<- struct{}) error {
+	f, err := os.Open(devRfkill)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer f.Close()
+
+		// newer kernels send longer events, but they're truncated to
+		// whatever size we read
+		event := make([]byte, rfkillEventSize)
+		for {
+			if _, err := f.Read(event); err != nil {
+				return
+			}
+			notify(c)
+		}
+	}()
+
+	return nil
+}
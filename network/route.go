@@ -0,0 +1,107 @@
+package network
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ipv4RouteFile = "/proc/net/route"
+	ipv6RouteFile = "/proc/net/ipv6_route"
+
+	rtfUp = 0x1 // RTF_UP: the route is usable
+)
+
+// defaultRouteInterface returns the interface of the default route with the
+// lowest metric, preferring IPv4. It returns an empty string if there is no
+// default route
+func defaultRouteInterface() string {
+	if iface := defaultIPv4Route(); iface != "" {
+		return iface
+	}
+	return defaultIPv6Route()
+}
+
+// defaultIPv4Route reads /proc/net/route, which looks like
+//
+//	Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+//	wlo1	00000000	0102A8C0	0003	0	0	600	00000000	0	0	0
+func defaultIPv4Route() string {
+	return bestRoute(ipv4RouteFile, func(fields []string) (string, uint64, bool) {
+		if len(fields) < 8 || fields[0] == "Iface" {
+			return "", 0, false
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&rtfUp == 0 || fields[1] != "00000000" || fields[7] != "00000000" {
+			return "", 0, false
+		}
+
+		metric, err := strconv.ParseUint(fields[6], 10, 32)
+		return fields[0], metric, err == nil
+	})
+}
+
+// defaultIPv6Route reads /proc/net/ipv6_route, where each line is the
+// destination, its prefix length, the source and its prefix length, the next
+// hop, the metric, the reference count, use count, flags and interface
+func defaultIPv6Route() string {
+	return bestRoute(ipv6RouteFile, func(fields []string) (string, uint64, bool) {
+		if len(fields) < 10 || fields[1] != "00" || strings.Trim(fields[0], "0") != "" {
+			return "", 0, false
+		}
+
+		flags, err := strconv.ParseUint(fields[8], 16, 32)
+		if err != nil || flags&rtfUp == 0 || fields[9] == "lo" {
+			return "", 0, false
+		}
+
+		metric, err := strconv.ParseUint(fields[5], 16, 32)
+		return fields[9], metric, err == nil
+	})
+}
+
+// bestRoute returns the interface of the route with the lowest metric in
+// file. parse returns the interface and metric of a line if it's a default
+// route
+func bestRoute(file string, parse func(fields []string) (iface string, metric uint64, ok bool)) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var (
+		best       string
+		bestMetric uint64
+	)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		iface, metric, ok := parse(strings.Fields(scanner.Text()))
+		if ok && (best == "" || metric < bestMetric) {
+			best, bestMetric = iface, metric
+		}
+	}
+
+	return best
+}
+
+// getWirelessInterfaces returns the names of every Wi-Fi interface
+func getWirelessInterfaces() []string {
+	infos, err := ioutil.ReadDir(sysClassNet)
+	if err != nil {
+		return nil
+	}
+
+	var ifaces []string
+	for _, info := range infos {
+		if isWireless(info.Name()) {
+			ifaces = append(ifaces, info.Name())
+		}
+	}
+	return ifaces
+}
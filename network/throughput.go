@@ -0,0 +1,150 @@
+package network
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/muni-corn/muse-status/utils"
+)
+
+// SecondaryMode is what the network block shows as secondary text while
+// connected
+type SecondaryMode int
+
+// Definitions for SecondaryMode
+const (
+	StatusMode     SecondaryMode = iota // connection problems, or the link speed
+	ThroughputMode                      // download and upload rates
+)
+
+const (
+	defaultThroughputWindow = time.Second * 3
+	throughputInterval      = time.Second // how often rates are sampled
+)
+
+type throughputSample struct {
+	at     time.Time
+	rx, tx uint64 // counters at the time
+}
+
+// throughput measures how fast an interface is sending and receiving, from
+// its statistics in sysfs. It's sampled by the block's broadcast and read by
+// queries, so everything is guarded by mutex
+type throughput struct {
+	mutex   sync.Mutex
+	iface   string
+	window  time.Duration // rates are averaged over this long
+	samples []throughputSample
+
+	// bytes since we started watching iface
+	sessionRx, sessionTx uint64
+}
+
+// sample reads iface's counters. switching interfaces starts a new session
+func (t *throughput) sample(iface string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if iface != t.iface {
+		t.iface, t.samples = iface, nil
+		t.sessionRx, t.sessionTx = 0, 0
+	}
+	if iface == "" {
+		return
+	}
+
+	dir := filepath.Join(sysClassNet, iface, "statistics")
+	rx, err := utils.GetIntFromFile(filepath.Join(dir, "rx_bytes"))
+	if err != nil {
+		return
+	}
+	tx, err := utils.GetIntFromFile(filepath.Join(dir, "tx_bytes"))
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	s := throughputSample{at: now, rx: uint64(rx), tx: uint64(tx)}
+
+	if n := len(t.samples); n > 0 {
+		last := t.samples[n-1]
+
+		// counters start over if the interface is re-created, which isn't
+		// traffic. start over with them
+		if s.rx < last.rx || s.tx < last.tx {
+			t.samples = nil
+		} else {
+			t.sessionRx += s.rx - last.rx
+			t.sessionTx += s.tx - last.tx
+		}
+	}
+	t.samples = append(t.samples, s)
+
+	// keep one sample at or past the start of the window, so rates cover
+	// all of it
+	window := t.window
+	if window <= 0 {
+		window = defaultThroughputWindow
+	}
+	for len(t.samples) > 2 && now.Sub(t.samples[1].at) >= window {
+		t.samples = t.samples[1:]
+	}
+}
+
+// rates returns the average download and upload rates over the window, in
+// bytes per second
+func (t *throughput) rates() (rx, tx float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.samples) < 2 {
+		return 0, 0
+	}
+
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	seconds := last.at.Sub(first.at).Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+
+	return float64(last.rx-first.rx) / seconds, float64(last.tx-first.tx) / seconds
+}
+
+// session returns the bytes received and sent since we started watching the
+// interface
+func (t *throughput) session() (rx, tx uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.sessionRx, t.sessionTx
+}
+
+// setWindow sets how long rates are averaged over
+func (t *throughput) setWindow(window time.Duration) {
+	t.mutex.Lock()
+	t.window = window
+	t.mutex.Unlock()
+}
+
+// text returns the rates as text, like "↓1.2 MB/s ↑34 kB/s"
+func (t *throughput) text() string {
+	rx, tx := t.rates()
+	return fmt.Sprintf("↓%s ↑%s", formatBytes(rx)+"/s", formatBytes(tx)+"/s")
+}
+
+// formatBytes returns an amount of bytes in human-readable (SI) units
+func formatBytes(bytes float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+
+	i := 0
+	for bytes >= 1000 && i < len(units)-1 {
+		bytes /= 1000
+		i++
+	}
+
+	if i == 0 || bytes >= 100 {
+		return fmt.Sprintf("%.0f %s", bytes, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[i])
+}
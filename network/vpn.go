@@ -0,0 +1,311 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/utils"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	vpnIcon = '\uf582'
+
+	// WireGuard renegotiates every two minutes while there's traffic, and
+	// gives up on keys after three. a handshake older than that means the
+	// peer isn't answering, but only if we've been talking to it; idle peers
+	// don't shake hands at all
+	staleHandshake = time.Minute * 3
+
+	vpnPollInterval = time.Second * 30
+	iffUp           = 0x1 // IFF_UP in /sys/class/net/<iface>/flags
+
+	// in /sys/class/net/<iface>/tun_flags, which only tun and tap devices
+	// have
+	iffTun = 0x1
+	iffTap = 0x2
+
+	arphrdPPP = 512 // the link type of PPP interfaces
+)
+
+// tunnel is an active VPN interface
+type tunnel struct {
+	name string
+	kind string
+
+	// only known for WireGuard tunnels, and only if we're allowed to ask
+	// (reading WireGuard devices needs CAP_NET_ADMIN)
+	lastHandshake  time.Time
+	handshakeKnown bool
+	stale          bool // a peer we're talking to hasn't answered
+}
+
+// peerStale returns true if a WireGuard peer should have shaken hands
+// recently but hasn't. that's if it's kept alive, or if we've sent it
+// something since the last poll (when it had sent lastTx bytes)
+func peerStale(p wgtypes.Peer, lastTx int64, polled bool, now time.Time) bool {
+	if now.Sub(p.LastHandshakeTime) <= staleHandshake {
+		return false
+	}
+	return p.PersistentKeepaliveInterval > 0 || (polled && p.TransmitBytes > lastTx)
+}
+
+// VPNBlock shows active VPN tunnels. It's hidden without any, and
+// warning-colored when a WireGuard handshake is stale
+type VPNBlock struct {
+	client  *wgctrl.Client // nil if WireGuard isn't available, or we can't read it
+	tunnels []tunnel
+	sent    map[string]int64 // bytes sent to each peer, by device and key, at the last poll
+
+	lastText  string
+	lastStale bool
+}
+
+// NewVPNBlock returns a new network.VPNBlock
+func NewVPNBlock() *VPNBlock {
+	b := &VPNBlock{}
+
+	if client, err := wgctrl.New(); err == nil {
+		b.client = client
+	}
+
+	return b
+}
+
+func (b *VPNBlock) StartBroadcast() <-chan bool {
+	c := make(chan bool)
+	go b.broadcast(c)
+	return c
+}
+
+func (b *VPNBlock) broadcast(c chan<- bool) {
+	events := watchNetwork()
+
+	for {
+		b.Update()
+		if b.shouldNotify() {
+			c <- true
+		}
+
+		// tunnels come and go with events, but handshakes have to be polled
+		var poll <-chan time.Time
+		if events == nil || (b.client != nil && b.hasWireGuard()) {
+			poll = time.After(vpnPollInterval)
+		}
+
+		select {
+		case <-events:
+		case <-poll:
+		}
+	}
+}
+
+func (b *VPNBlock) shouldNotify() bool {
+	primary, secondary := b.Text()
+	text, stale := primary+secondary, b.stale()
+	if text != b.lastText || stale != b.lastStale {
+		b.lastText, b.lastStale = text, stale
+		return true
+	}
+	return false
+}
+
+func (b *VPNBlock) hasWireGuard() bool {
+	for _, t := range b.tunnels {
+		if t.kind == "wireguard" {
+			return true
+		}
+	}
+	return false
+}
+
+// stale returns true if any tunnel is stale
+func (b *VPNBlock) stale() bool {
+	for _, t := range b.tunnels {
+		if t.stale {
+			return true
+		}
+	}
+	return false
+}
+
+// Update finds active tunnels
+func (b *VPNBlock) Update() {
+	infos, err := ioutil.ReadDir(sysClassNet)
+	if err != nil {
+		b.tunnels = nil
+		return
+	}
+
+	var tunnels []tunnel
+	sent := make(map[string]int64)
+	for _, info := range infos {
+		name := info.Name()
+		kind := tunnelKind(filepath.Join(sysClassNet, name))
+		if kind == "" {
+			continue
+		}
+
+		flags, err := utils.GetStringFromFile(filepath.Join(sysClassNet, name, "flags"))
+		if err != nil {
+			continue
+		}
+		if f, err := strconv.ParseInt(flags, 0, 64); err != nil || f&iffUp == 0 {
+			continue
+		}
+
+		t := tunnel{name: name, kind: kind}
+		if kind == "wireguard" {
+			t.lastHandshake, t.stale, t.handshakeKnown = b.handshake(name, sent)
+		}
+		tunnels = append(tunnels, t)
+	}
+
+	b.tunnels, b.sent = tunnels, sent
+}
+
+// handshake returns the most recent handshake with any of a WireGuard
+// device's peers, and whether any peer is stale. the bytes sent to each peer
+// are recorded in sent for the next poll. ok is false if the device can't be
+// read. without permission to read it, we stop trying and only show whether
+// tunnels are up
+func (b *VPNBlock) handshake(name string, sent map[string]int64) (last time.Time, stale, ok bool) {
+	if b.client == nil {
+		return
+	}
+
+	device, err := b.client.Device(name)
+	if errors.Is(err, os.ErrPermission) {
+		b.client.Close()
+		b.client = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, p := range device.Peers {
+		key := name + "/" + p.PublicKey.String()
+		lastTx, polled := b.sent[key]
+		sent[key] = p.TransmitBytes
+
+		if p.LastHandshakeTime.After(last) {
+			last = p.LastHandshakeTime
+		}
+		if peerStale(p, lastTx, polled, now) {
+			stale = true
+		}
+	}
+	return last, stale, true
+}
+
+// tunnelKind returns the kind of tunnel the interface with sysfs directory
+// dir is, or an empty string if it isn't one. names can't be trusted: tunl0,
+// for one, is the kernel's IPIP device and not a tun device
+func tunnelKind(dir string) string {
+	if uevent, err := ioutil.ReadFile(filepath.Join(dir, "uevent")); err == nil {
+		for _, line := range strings.Split(string(uevent), "\n") {
+			if line == "DEVTYPE=wireguard" {
+				return "wireguard"
+			}
+		}
+	}
+
+	if flags, err := utils.GetStringFromFile(filepath.Join(dir, "tun_flags")); err == nil {
+		f, err := strconv.ParseInt(flags, 0, 64)
+		switch {
+		case err != nil:
+		case f&iffTun != 0:
+			return "tun"
+		case f&iffTap != 0:
+			return "tap"
+		}
+	}
+
+	if t, err := utils.GetIntFromFile(filepath.Join(dir, "type")); err == nil && t == arphrdPPP {
+		return "ppp"
+	}
+
+	return ""
+}
+
+// Name returns "vpn"
+func (b *VPNBlock) Name() string {
+	return "vpn"
+}
+
+// Icon returns the VPN icon
+func (b *VPNBlock) Icon() rune {
+	return vpnIcon
+}
+
+// Text returns the tunnel names as primary. secondary text says how long ago
+// a stale tunnel last shook hands
+func (b *VPNBlock) Text() (primary, secondary string) {
+	names := make([]string, len(b.tunnels))
+	for i, t := range b.tunnels {
+		names[i] = t.name
+		if t.stale && secondary == "" {
+			if t.lastHandshake.IsZero() {
+				secondary = "No handshake"
+			} else {
+				secondary = fmt.Sprintf("No handshake for %d min", int(time.Since(t.lastHandshake).Minutes()))
+			}
+		}
+	}
+
+	return strings.Join(names, ", "), secondary
+}
+
+// Colorer returns the warning colorer if a handshake is stale
+func (b *VPNBlock) Colorer() format.Colorer {
+	if b.stale() {
+		return format.GetWarningColorer()
+	}
+	return format.GetDefaultColorer()
+}
+
+// Hidden returns true if there aren't any tunnels
+func (b *VPNBlock) Hidden() bool {
+	return len(b.tunnels) == 0
+}
+
+// ForceShort returns false
+func (b *VPNBlock) ForceShort() bool {
+	return false
+}
+
+func (b *VPNBlock) Output(mode format.Mode) string {
+	return format.FormatClassicBlock(b)
+}
+
+// Query returns every active tunnel
+func (b *VPNBlock) Query() map[string]interface{} {
+	tunnels := make([]map[string]interface{}, len(b.tunnels))
+	for i, t := range b.tunnels {
+		info := map[string]interface{}{
+			"name": t.name,
+			"type": t.kind,
+		}
+		if t.handshakeKnown {
+			info["stale"] = t.stale
+			if !t.lastHandshake.IsZero() {
+				info["last_handshake"] = t.lastHandshake.Format(time.RFC3339)
+			}
+		}
+		tunnels[i] = info
+	}
+
+	return map[string]interface{}{
+		"active":  len(b.tunnels) > 0,
+		"tunnels": tunnels,
+	}
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func TestPeerStale(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name      string
+		handshake time.Time
+		keepalive time.Duration
+		tx        int64
+		lastTx    int64
+		polled    bool
+		want      bool
+	}{
+		{"fresh", now.Add(-time.Minute), 0, 200, 100, true, false},
+		{"idle", now.Add(-time.Hour), 0, 100, 100, true, false},
+		{"never used", time.Time{}, 0, 0, 0, true, false},
+		{"first poll", now.Add(-time.Hour), 0, 100, 0, false, false},
+		{"sending", now.Add(-time.Hour), 0, 200, 100, true, true},
+		{"sending, never answered", time.Time{}, 0, 200, 100, true, true},
+		{"kept alive", now.Add(-time.Hour), time.Second * 25, 100, 100, true, true},
+		{"kept alive and fresh", now.Add(-time.Minute), time.Second * 25, 100, 100, true, false},
+	} {
+		p := wgtypes.Peer{
+			LastHandshakeTime:           test.handshake,
+			PersistentKeepaliveInterval: test.keepalive,
+			TransmitBytes:               test.tx,
+		}
+		if got := peerStale(p, test.lastTx, test.polled, now); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTunnelKind(t *testing.T) {
+	root := t.TempDir()
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"wg0", map[string]string{"uevent": "DEVTYPE=wireguard\nINTERFACE=wg0\nIFINDEX=5\n", "type": "65534"}, "wireguard"},
+		{"mullvad", map[string]string{"uevent": "DEVTYPE=wireguard\nINTERFACE=mullvad\n", "type": "65534"}, "wireguard"},
+		{"tun0", map[string]string{"uevent": "INTERFACE=tun0\n", "tun_flags": "0x1001", "type": "65534"}, "tun"},
+		{"tap0", map[string]string{"uevent": "INTERFACE=tap0\n", "tun_flags": "0x1002", "type": "1"}, "tap"},
+		{"ppp0", map[string]string{"uevent": "INTERFACE=ppp0\n", "type": "512"}, "ppp"},
+		{"tunl0", map[string]string{"uevent": "INTERFACE=tunl0\n", "type": "768"}, ""},
+		{"eth0", map[string]string{"uevent": "INTERFACE=eth0\n", "type": "1"}, ""},
+	} {
+		dir := filepath.Join(root, test.name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for file, value := range test.files {
+			if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := tunnelKind(dir); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
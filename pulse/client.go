@@ -0,0 +1,322 @@
+// Package pulse is a small client for the PulseAudio native protocol, which
+// is also served by pipewire-pulse. It only implements what muse-status
+// needs: reading and changing devices, and subscribing to changes
+package pulse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// protocolVersion is the version of the native protocol we speak. 34 adds
+// port types
+const protocolVersion = 34
+
+const (
+	commandChannel = 0xFFFFFFFF // channel for control packets, as opposed to audio
+	invalidIndex   = 0xFFFFFFFF
+	cookieLength   = 256
+	requestTimeout = time.Second * 5
+)
+
+// commands of the native protocol
+const (
+	commandError                   = 0
+	commandReply                   = 2
+	commandAuth                    = 8
+	commandSetClientName           = 9
+	commandGetServerInfo           = 20
+	commandGetSinkInfo             = 21
+	commandGetSinkInfoList         = 22
+	commandGetSourceInfo           = 23
+	commandGetSourceInfoList       = 24
+	commandGetSourceOutputInfoList = 32
+	commandSubscribe               = 35
+	commandSetSinkVolume           = 36
+	commandSetSinkMute             = 39
+	commandSetSourceMute           = 40
+	commandSetDefaultSink          = 44
+	commandSubscribeEvent          = 66
+)
+
+// ErrClosed is returned for requests made after the connection is lost
+var ErrClosed = errors.New("pulse: connection closed")
+
+// Error is an error returned by the server
+type Error uint32
+
+func (e Error) Error() string {
+	switch e {
+	case 1:
+		return "pulse: access denied"
+	case 2:
+		return "pulse: unknown command"
+	case 3:
+		return "pulse: invalid argument"
+	case 5:
+		return "pulse: no such entity"
+	default:
+		return fmt.Sprintf("pulse: server error %d", uint32(e))
+	}
+}
+
+// Client is a connection to a PulseAudio server
+type Client struct {
+	conn    net.Conn
+	version uint32 // negotiated protocol version
+
+	mutex   sync.Mutex
+	nextTag uint32
+	replies map[uint32]chan *tagReader
+
+	events chan Event
+	closed chan struct{}
+}
+
+// Dial connects to the user's PulseAudio server, introducing ourselves as
+// appName
+func Dial(appName string) (*Client, error) {
+	path, err := serverPath()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:    conn,
+		version: protocolVersion,
+		replies: make(map[uint32]chan *tagReader),
+		events:  make(chan Event, 16),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop()
+
+	// authenticate
+	r, err := c.request(commandAuth, new(tagWriter).u32(protocolVersion).arbitrary(readCookie()))
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	serverVersion, err := r.u32()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	// the upper bits are flags for shared memory, which we don't use
+	if v := serverVersion & 0xFFFF; v < c.version {
+		c.version = v
+	}
+
+	props := map[string]string{"application.name": appName}
+	if _, err := c.request(commandSetClientName, new(tagWriter).propList(props)); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Closed returns a channel that is closed when the connection is lost
+func (c *Client) Closed() <-chan struct{} {
+	return c.closed
+}
+
+// request sends a command and waits for its reply
+func (c *Client) request(command uint32, args *tagWriter) (*tagReader, error) {
+	c.mutex.Lock()
+	tag := c.nextTag
+	c.nextTag++
+	replyChan := make(chan *tagReader, 1)
+	c.replies[tag] = replyChan
+
+	// every command starts with the command and its tag
+	packet := new(tagWriter).u32(command).u32(tag)
+	if args != nil {
+		packet.buf.Write(args.buf.Bytes())
+	}
+	err := c.writePacket(packet.buf.Bytes())
+	c.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-replyChan:
+		cmd, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.u32(); err != nil { // tag
+			return nil, err
+		}
+
+		if cmd == commandError {
+			code, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			return nil, Error(code)
+		}
+		return r, nil
+	case <-c.closed:
+		return nil, ErrClosed
+	case <-time.After(requestTimeout):
+		c.mutex.Lock()
+		delete(c.replies, tag)
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("pulse: request %d timed out", command)
+	}
+}
+
+// writePacket writes a control packet. c.mutex must be held
+func (c *Client) writePacket(payload []byte) error {
+	// length, channel, offset high, offset low, flags
+	descriptor := make([]byte, 20)
+	binary.BigEndian.PutUint32(descriptor[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(descriptor[4:], commandChannel)
+
+	_, err := c.conn.Write(append(descriptor, payload...))
+	return err
+}
+
+// readLoop reads packets from the server, passing replies to whoever is
+// waiting for them and events to the events channel
+func (c *Client) readLoop() {
+	defer close(c.closed)
+	defer close(c.events)
+
+	descriptor := make([]byte, 20)
+	for {
+		if _, err := io.ReadFull(c.conn, descriptor); err != nil {
+			return
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(descriptor[0:]))
+		if _, err := io.ReadFull(c.conn, payload); err != nil {
+			return
+		}
+
+		// we don't open streams, so anything else isn't for us
+		if binary.BigEndian.Uint32(descriptor[4:]) != commandChannel {
+			continue
+		}
+
+		c.handlePacket(payload)
+	}
+}
+
+func (c *Client) handlePacket(payload []byte) {
+	// peek at the command and tag without consuming them
+	peek := &tagReader{data: payload}
+	cmd, err := peek.u32()
+	if err != nil {
+		return
+	}
+	tag, err := peek.u32()
+	if err != nil {
+		return
+	}
+
+	switch cmd {
+	case commandReply, commandError:
+		c.mutex.Lock()
+		replyChan, ok := c.replies[tag]
+		delete(c.replies, tag)
+		c.mutex.Unlock()
+
+		if ok {
+			replyChan <- &tagReader{data: payload}
+		}
+	case commandSubscribeEvent:
+		e, err := readEvent(peek)
+		if err != nil {
+			return
+		}
+
+		// events only say that something changed, so dropping some while
+		// the receiver is busy loses nothing
+		select {
+		case c.events <- e:
+		default:
+		}
+	}
+}
+
+// serverPath returns the path of the server's socket. PULSE_SERVER may list
+// several servers, separated by spaces; the first unix socket is used. we
+// don't speak TCP, so an error is returned if there isn't one
+func serverPath() (string, error) {
+	if server := os.Getenv("PULSE_SERVER"); server != "" {
+		for _, s := range strings.Fields(server) {
+			// servers may be limited to a machine, like "{id}unix:/path"
+			if strings.HasPrefix(s, "{") {
+				end := strings.IndexByte(s, '}')
+				if end < 0 {
+					continue
+				}
+				s = s[end+1:]
+			}
+
+			if path := strings.TrimPrefix(s, "unix:"); strings.HasPrefix(path, "/") {
+				return path, nil
+			}
+		}
+		return "", fmt.Errorf("pulse: unsupported PULSE_SERVER %q; only unix sockets are supported", server)
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+
+	return filepath.Join(runtimeDir, "pulse", "native"), nil
+}
+
+// readCookie returns the authentication cookie shared with the server. if
+// there isn't one, an empty cookie is returned; servers that don't need one
+// (like pipewire-pulse) accept it anyway
+func readCookie() []byte {
+	var paths []string
+	if p := os.Getenv("PULSE_COOKIE"); p != "" {
+		paths = append(paths, p)
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(configDir, "pulse", "cookie"))
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(home, ".pulse-cookie"))
+	}
+
+	for _, p := range paths {
+		cookie, err := ioutil.ReadFile(p)
+		if err == nil && len(cookie) == cookieLength {
+			return cookie
+		}
+	}
+
+	return make([]byte, cookieLength)
+}
@@ -0,0 +1,292 @@
+package pulse
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	testSpeakers = Device{
+		Index:       0,
+		Name:        "alsa_output.pci-0000_00_1f.3.analog-stereo",
+		Description: "Built-in Audio Analog Stereo",
+		Volume:      []uint32{VolumeNorm / 2, VolumeNorm * 3 / 4},
+		Props:       map[string]string{"device.class": "sound", "device.form_factor": "internal"},
+		Ports: []Port{
+			{Name: "analog-output-speaker", Description: "Speakers", Priority: 10000, Available: 1, Type: PortSpeaker},
+			{Name: "analog-output-headphones", Description: "Headphones", Priority: 9900, Available: 2, Type: PortHeadphones},
+		},
+		ActivePort: "analog-output-headphones",
+	}
+	testHeadset = Device{
+		Index:       1,
+		Name:        "bluez_output.00_11_22_33_44_55.1",
+		Description: "Headset",
+		Volume:      []uint32{VolumeNorm, VolumeNorm},
+		Mute:        true,
+		Props:       map[string]string{"device.bus": "bluetooth"},
+		Ports: []Port{
+			{Name: "headset-output", Description: "Headset", Priority: 0, Available: 2, Type: PortHeadset},
+		},
+		ActivePort: "headset-output",
+	}
+	testMic = Device{
+		Index:       2,
+		Name:        "alsa_input.pci-0000_00_1f.3.analog-stereo",
+		Description: "Built-in Audio Analog Stereo",
+		Volume:      []uint32{VolumeNorm, VolumeNorm},
+		Props:       map[string]string{"device.class": "sound"},
+		Ports: []Port{
+			{Name: "analog-input-mic", Description: "Microphone", Priority: 8700, Available: 1, Type: PortMic},
+		},
+		ActivePort: "analog-input-mic",
+	}
+	testMonitor = Device{
+		Index:       3,
+		Name:        "alsa_output.pci-0000_00_1f.3.analog-stereo.monitor",
+		Description: "Monitor of Built-in Audio Analog Stereo",
+		Volume:      []uint32{VolumeNorm, VolumeNorm},
+		Props:       map[string]string{"device.class": "monitor"},
+		Monitor:     true,
+	}
+)
+
+// dialFake starts a fake server speaking version, with some devices, and
+// connects to it
+func dialFake(t *testing.T, version uint32) (*Client, *fakeServer) {
+	t.Helper()
+
+	s := startFakeServer(t, version)
+	s.info = ServerInfo{
+		PackageName:    "pulseaudio",
+		PackageVersion: "16.1",
+		DefaultSink:    testSpeakers.Name,
+		DefaultSource:  testMic.Name,
+	}
+	s.sinks = []Device{testSpeakers, testHeadset}
+	s.sources = []Device{testMic, testMonitor}
+
+	c, err := Dial("muse-status-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c, s
+}
+
+// withoutPortTypes returns d as a server older than version 34 reports it
+func withoutPortTypes(d Device) Device {
+	ports := make([]Port, len(d.Ports))
+	for i, p := range d.Ports {
+		p.Type = PortUnknown
+		ports[i] = p
+	}
+	d.Ports = ports
+	return d
+}
+
+func TestServerPath(t *testing.T) {
+	for _, test := range []struct {
+		server string
+		path   string
+		ok     bool
+	}{
+		{"unix:/run/user/1000/pulse/native", "/run/user/1000/pulse/native", true},
+		{"/tmp/pulse.sock", "/tmp/pulse.sock", true},
+		{"{abc123}unix:/tmp/pulse.sock", "/tmp/pulse.sock", true},
+		{"tcp:localhost:4713 unix:/tmp/pulse.sock", "/tmp/pulse.sock", true},
+		{"tcp:localhost:4713", "", false},
+		{"localhost", "", false},
+		{"{abc123", "", false},
+	} {
+		t.Setenv("PULSE_SERVER", test.server)
+		path, err := serverPath()
+		if (err == nil) != test.ok || path != test.path {
+			t.Errorf("PULSE_SERVER=%q: got %q, %v", test.server, path, err)
+		}
+	}
+}
+
+func TestDialUnsupportedServer(t *testing.T) {
+	t.Setenv("PULSE_SERVER", "tcp:localhost:4713")
+	if c, err := Dial("muse-status-test"); err == nil {
+		c.Close()
+		t.Fatal("expected an error for a TCP server")
+	}
+}
+
+func TestDial(t *testing.T) {
+	for _, version := range []uint32{32, 34, 35} {
+		c, s := dialFake(t, version)
+
+		want := version
+		if want > protocolVersion {
+			want = protocolVersion
+		}
+		if c.version != want {
+			t.Errorf("server version %d: negotiated %d, want %d", version, c.version, want)
+		}
+
+		s.mutex.Lock()
+		if len(s.authCookie) != cookieLength {
+			t.Errorf("cookie is %d bytes, want %d", len(s.authCookie), cookieLength)
+		}
+		if s.clientName != "muse-status-test" {
+			t.Errorf("client name is %q", s.clientName)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	c, s := dialFake(t, 34)
+
+	info, err := c.ServerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != s.info {
+		t.Errorf("got %+v, want %+v", info, s.info)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	for _, test := range []struct {
+		version uint32
+		want    []Device
+	}{
+		{32, []Device{withoutPortTypes(testSpeakers), withoutPortTypes(testHeadset)}},
+		{34, []Device{testSpeakers, testHeadset}},
+	} {
+		c, _ := dialFake(t, test.version)
+
+		sinks, err := c.Sinks()
+		if err != nil {
+			t.Fatalf("version %d: %v", test.version, err)
+		}
+		if !reflect.DeepEqual(sinks, test.want) {
+			t.Errorf("version %d: got %+v, want %+v", test.version, sinks, test.want)
+		}
+	}
+}
+
+func TestSink(t *testing.T) {
+	c, _ := dialFake(t, 34)
+
+	sink, err := c.Sink(DefaultSink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sink, testSpeakers) {
+		t.Errorf("got %+v, want %+v", sink, testSpeakers)
+	}
+	if p, ok := sink.Port(); !ok || p.Type != PortHeadphones {
+		t.Errorf("active port is %+v", p)
+	}
+	if v := sink.VolumePercent(); v != 75 {
+		t.Errorf("volume is %d%%, want the loudest channel, 75%%", v)
+	}
+
+	sink, err = c.Sink(testHeadset.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sink.Mute || sink.Props["device.bus"] != "bluetooth" {
+		t.Errorf("got %+v", sink)
+	}
+
+	if _, err := c.Sink("nonexistent"); err != Error(5) {
+		t.Errorf("error is %v, want %v", err, Error(5))
+	}
+}
+
+func TestSource(t *testing.T) {
+	for _, test := range []struct {
+		version uint32
+		want    Device
+	}{
+		{32, withoutPortTypes(testMic)},
+		{34, testMic},
+	} {
+		c, _ := dialFake(t, test.version)
+
+		source, err := c.Source(DefaultSource)
+		if err != nil {
+			t.Fatalf("version %d: %v", test.version, err)
+		}
+		if !reflect.DeepEqual(source, test.want) {
+			t.Errorf("version %d: got %+v, want %+v", test.version, source, test.want)
+		}
+
+		sources, err := c.Sources()
+		if err != nil {
+			t.Fatalf("version %d: %v", test.version, err)
+		}
+		if len(sources) != 2 || sources[0].Monitor || !sources[1].Monitor {
+			t.Errorf("version %d: sources are %+v", test.version, sources)
+		}
+	}
+}
+
+func TestSetSinkVolume(t *testing.T) {
+	c, s := dialFake(t, 34)
+
+	volume := []uint32{VolumeNorm / 4, VolumeNorm / 4}
+	if err := c.SetSinkVolume(DefaultSink, volume); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mutex.Lock()
+	got := s.volumes[testSpeakers.Name]
+	s.mutex.Unlock()
+	if !reflect.DeepEqual(got, volume) {
+		t.Errorf("server got %v, want %v", got, volume)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c, s := dialFake(t, 34)
+
+	events, err := c.Subscribe(SubscribeSink | SubscribeServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-s.subscribed
+
+	s.mutex.Lock()
+	mask := s.mask
+	s.mutex.Unlock()
+	if mask != SubscribeSink|SubscribeServer {
+		t.Errorf("server got mask %#x", mask)
+	}
+
+	s.sendEvent(FacilitySink, EventChange, 1)
+	s.sendEvent(FacilityServer, EventChange, invalidIndex)
+
+	for _, want := range []Event{
+		{Facility: FacilitySink, Type: EventChange, Index: 1},
+		{Facility: FacilityServer, Type: EventChange, Index: invalidIndex},
+	} {
+		select {
+		case e := <-events:
+			if e != want {
+				t.Errorf("got %+v, want %+v", e, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event; want %+v", want)
+		}
+	}
+
+	// the channel is closed when the connection is lost
+	c.Close()
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(time.Second):
+		t.Fatal("events weren't closed")
+	}
+}
@@ -0,0 +1,435 @@
+package pulse
+
+// VolumeNorm is the volume of a channel at 100%
+const VolumeNorm = 0x10000
+
+// default device names, understood by the server in place of real names
+const (
+	DefaultSink   = "@DEFAULT_SINK@"
+	DefaultSource = "@DEFAULT_SOURCE@"
+)
+
+// ServerInfo is information about the server
+type ServerInfo struct {
+	PackageName    string
+	PackageVersion string
+	DefaultSink    string
+	DefaultSource  string
+}
+
+// PortType is the kind of thing a port connects to. Servers older than
+// protocol version 34 don't report it
+type PortType uint32
+
+// Definitions for PortType
+const (
+	PortUnknown PortType = iota
+	PortAux
+	PortSpeaker
+	PortHeadphones
+	PortLine
+	PortMic
+	PortHeadset
+	PortHandset
+	PortEarpiece
+	PortSPDIF
+	PortHDMI
+	PortTV
+	PortRadio
+	PortVideo
+	PortUSB
+	PortBluetooth
+	PortPortable
+	PortHandsfree
+	PortCar
+	PortHiFi
+	PortPhone
+	PortNetwork
+	PortAnalog
+)
+
+// Port is a jack or connection on a device, like speakers or headphones
+type Port struct {
+	Name        string
+	Description string
+	Priority    uint32
+	Available   uint32
+	Type        PortType
+}
+
+// Device is a sink (output) or source (input)
+type Device struct {
+	Index       uint32
+	Name        string
+	Description string
+	Volume      []uint32 // per channel
+	Mute        bool
+	Props       map[string]string
+	Ports       []Port
+	ActivePort  string
+
+	// Monitor is true for sources that record what a sink plays, rather than
+	// a real input
+	Monitor bool
+}
+
+// SourceOutput is a stream recording from a source
+type SourceOutput struct {
+	Index  uint32
+	Name   string
+	Source uint32 // index of the source
+	Props  map[string]string
+	Corked bool // paused
+}
+
+// Port returns the device's active port, if it has one
+func (d Device) Port() (Port, bool) {
+	for _, p := range d.Ports {
+		if p.Name == d.ActivePort {
+			return p, true
+		}
+	}
+	return Port{}, false
+}
+
+// VolumePercent returns the volume of the loudest channel as a percentage.
+// It can be more than 100 if the device is boosted
+func (d Device) VolumePercent() int {
+	var max uint32
+	for _, v := range d.Volume {
+		if v > max {
+			max = v
+		}
+	}
+
+	return int((uint64(max)*100 + VolumeNorm/2) / VolumeNorm)
+}
+
+// ServerInfo returns information about the server, including the default
+// devices
+func (c *Client) ServerInfo() (info ServerInfo, err error) {
+	r, err := c.request(commandGetServerInfo, nil)
+	if err != nil {
+		return
+	}
+
+	if info.PackageName, err = r.str(); err != nil {
+		return
+	}
+	if info.PackageVersion, err = r.str(); err != nil {
+		return
+	}
+	if _, err = r.str(); err != nil { // user name
+		return
+	}
+	if _, err = r.str(); err != nil { // host name
+		return
+	}
+	if err = r.sampleSpec(); err != nil {
+		return
+	}
+	if info.DefaultSink, err = r.str(); err != nil {
+		return
+	}
+	info.DefaultSource, err = r.str()
+	return
+}
+
+// Sink returns the sink with the given name. Use DefaultSink for the default
+// one
+func (c *Client) Sink(name string) (Device, error) {
+	r, err := c.request(commandGetSinkInfo, new(tagWriter).u32(invalidIndex).str(name))
+	if err != nil {
+		return Device{}, err
+	}
+
+	return readDevice(r, c.version, true)
+}
+
+// Sinks returns every sink
+func (c *Client) Sinks() ([]Device, error) {
+	r, err := c.request(commandGetSinkInfoList, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sinks []Device
+	for !r.empty() {
+		d, err := readDevice(r, c.version, true)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, d)
+	}
+
+	return sinks, nil
+}
+
+// SetDefaultSink makes the named sink the default
+func (c *Client) SetDefaultSink(name string) error {
+	_, err := c.request(commandSetDefaultSink, new(tagWriter).str(name))
+	return err
+}
+
+// Source returns the source with the given name. Use DefaultSource for the
+// default one
+func (c *Client) Source(name string) (Device, error) {
+	r, err := c.request(commandGetSourceInfo, new(tagWriter).u32(invalidIndex).str(name))
+	if err != nil {
+		return Device{}, err
+	}
+
+	return readDevice(r, c.version, false)
+}
+
+// Sources returns every source, including monitors
+func (c *Client) Sources() ([]Device, error) {
+	r, err := c.request(commandGetSourceInfoList, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []Device
+	for !r.empty() {
+		d, err := readDevice(r, c.version, false)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, d)
+	}
+
+	return sources, nil
+}
+
+// SourceOutputs returns every stream that is recording
+func (c *Client) SourceOutputs() ([]SourceOutput, error) {
+	r, err := c.request(commandGetSourceOutputInfoList, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []SourceOutput
+	for !r.empty() {
+		o, err := readSourceOutput(r, c.version)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, o)
+	}
+
+	return outputs, nil
+}
+
+// SetSourceMute mutes or unmutes a source
+func (c *Client) SetSourceMute(name string, mute bool) error {
+	_, err := c.request(commandSetSourceMute, new(tagWriter).u32(invalidIndex).str(name).boolean(mute))
+	return err
+}
+
+// SetSinkVolume sets the volume of each channel of a sink
+func (c *Client) SetSinkVolume(name string, volume []uint32) error {
+	_, err := c.request(commandSetSinkVolume, new(tagWriter).u32(invalidIndex).str(name).cvolume(volume))
+	return err
+}
+
+// SetSinkMute mutes or unmutes a sink
+func (c *Client) SetSinkMute(name string, mute bool) error {
+	_, err := c.request(commandSetSinkMute, new(tagWriter).u32(invalidIndex).str(name).boolean(mute))
+	return err
+}
+
+// readDevice reads sink or source info. The two only differ in which device
+// they're paired with (a sink's monitor source, or a source's monitored
+// sink), which we skip
+func readDevice(r *tagReader, version uint32, sink bool) (d Device, err error) {
+	if d.Index, err = r.u32(); err != nil {
+		return
+	}
+	if d.Name, err = r.str(); err != nil {
+		return
+	}
+	if d.Description, err = r.str(); err != nil {
+		return
+	}
+	if err = r.sampleSpec(); err != nil {
+		return
+	}
+	if err = r.channelMap(); err != nil {
+		return
+	}
+	if _, err = r.u32(); err != nil { // owner module
+		return
+	}
+	if d.Volume, err = r.cvolume(); err != nil {
+		return
+	}
+	if d.Mute, err = r.boolean(); err != nil {
+		return
+	}
+	var paired uint32
+	if paired, err = r.u32(); err != nil { // monitor source, or monitored sink
+		return
+	}
+	d.Monitor = !sink && paired != invalidIndex
+	if _, err = r.str(); err != nil { // and its name
+		return
+	}
+	if _, err = r.usec(); err != nil { // latency
+		return
+	}
+	if _, err = r.str(); err != nil { // driver
+		return
+	}
+	if _, err = r.u32(); err != nil { // flags
+		return
+	}
+
+	if version >= 13 {
+		if d.Props, err = r.propList(); err != nil {
+			return
+		}
+		if _, err = r.usec(); err != nil { // configured latency
+			return
+		}
+	}
+
+	if version >= 15 {
+		if _, err = r.volume(); err != nil { // base volume
+			return
+		}
+		for i := 0; i < 3; i++ { // state, volume steps, card
+			if _, err = r.u32(); err != nil {
+				return
+			}
+		}
+	}
+
+	if version >= 16 {
+		var n uint32
+		if n, err = r.u32(); err != nil {
+			return
+		}
+
+		d.Ports = make([]Port, n)
+		for i := range d.Ports {
+			if d.Ports[i], err = readPort(r, version); err != nil {
+				return
+			}
+		}
+
+		if d.ActivePort, err = r.str(); err != nil {
+			return
+		}
+	}
+
+	// sinks list their formats from version 21, sources from 22
+	if (sink && version >= 21) || (!sink && version >= 22) {
+		var n uint8
+		if n, err = r.u8(); err != nil {
+			return
+		}
+		for i := uint8(0); i < n; i++ {
+			if err = r.formatInfo(); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func readSourceOutput(r *tagReader, version uint32) (o SourceOutput, err error) {
+	if o.Index, err = r.u32(); err != nil {
+		return
+	}
+	if o.Name, err = r.str(); err != nil {
+		return
+	}
+	if _, err = r.u32(); err != nil { // owner module
+		return
+	}
+	if _, err = r.u32(); err != nil { // client
+		return
+	}
+	if o.Source, err = r.u32(); err != nil {
+		return
+	}
+	if err = r.sampleSpec(); err != nil {
+		return
+	}
+	if err = r.channelMap(); err != nil {
+		return
+	}
+	if _, err = r.usec(); err != nil { // buffer latency
+		return
+	}
+	if _, err = r.usec(); err != nil { // source latency
+		return
+	}
+	if _, err = r.str(); err != nil { // resample method
+		return
+	}
+	if _, err = r.str(); err != nil { // driver
+		return
+	}
+
+	if version >= 13 {
+		if o.Props, err = r.propList(); err != nil {
+			return
+		}
+	}
+
+	if version >= 19 {
+		if o.Corked, err = r.boolean(); err != nil {
+			return
+		}
+	}
+
+	if version >= 22 {
+		if _, err = r.cvolume(); err != nil {
+			return
+		}
+		for i := 0; i < 3; i++ { // muted, has volume, volume writable
+			if _, err = r.boolean(); err != nil {
+				return
+			}
+		}
+		if err = r.formatInfo(); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func readPort(r *tagReader, version uint32) (p Port, err error) {
+	if p.Name, err = r.str(); err != nil {
+		return
+	}
+	if p.Description, err = r.str(); err != nil {
+		return
+	}
+	if p.Priority, err = r.u32(); err != nil {
+		return
+	}
+
+	if version >= 24 {
+		if p.Available, err = r.u32(); err != nil {
+			return
+		}
+	}
+
+	if version >= 34 {
+		if _, err = r.str(); err != nil { // availability group
+			return
+		}
+
+		var t uint32
+		if t, err = r.u32(); err != nil {
+			return
+		}
+		p.Type = PortType(t)
+	}
+
+	return
+}
@@ -0,0 +1,77 @@
+package pulse
+
+// Facility is the kind of object an Event is about
+type Facility uint32
+
+// Definitions for Facility
+const (
+	FacilitySink         Facility = 0
+	FacilitySource       Facility = 1
+	FacilitySinkInput    Facility = 2
+	FacilitySourceOutput Facility = 3
+	FacilityModule       Facility = 4
+	FacilityClient       Facility = 5
+	FacilitySampleCache  Facility = 6
+	FacilityServer       Facility = 7
+	FacilityCard         Facility = 9
+)
+
+// EventType is what happened to the object an Event is about
+type EventType uint32
+
+// Definitions for EventType
+const (
+	EventNew    EventType = 0x00
+	EventChange EventType = 0x10
+	EventRemove EventType = 0x20
+)
+
+const (
+	facilityMask  = 0x0F
+	eventTypeMask = 0x30
+)
+
+// SubscriptionMask selects which facilities to receive events for
+type SubscriptionMask uint32
+
+// Definitions for SubscriptionMask
+const (
+	SubscribeSink         SubscriptionMask = 1 << FacilitySink
+	SubscribeSource       SubscriptionMask = 1 << FacilitySource
+	SubscribeSinkInput    SubscriptionMask = 1 << FacilitySinkInput
+	SubscribeSourceOutput SubscriptionMask = 1 << FacilitySourceOutput
+	SubscribeServer       SubscriptionMask = 1 << FacilityServer
+	SubscribeCard         SubscriptionMask = 1 << FacilityCard
+)
+
+// Event tells that an object on the server has changed
+type Event struct {
+	Facility Facility
+	Type     EventType
+	Index    uint32
+}
+
+func readEvent(r *tagReader) (e Event, err error) {
+	t, err := r.u32()
+	if err != nil {
+		return
+	}
+	e.Index, err = r.u32()
+
+	e.Facility = Facility(t & facilityMask)
+	e.Type = EventType(t & eventTypeMask)
+	return
+}
+
+// Subscribe asks the server for events about the facilities in mask. It
+// returns a channel of events, which is closed when the connection is lost.
+// Events may be dropped if they aren't received quickly, so they should be
+// used as a hint to re-read state rather than as a log of changes
+func (c *Client) Subscribe(mask SubscriptionMask) (<-chan Event, error) {
+	_, err := c.request(commandSubscribe, new(tagWriter).u32(uint32(mask)))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.events, nil
+}
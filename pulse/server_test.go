@@ -0,0 +1,329 @@
+package pulse
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeServer speaks enough of the native protocol to stand in for
+// PulseAudio. it encodes replies in the server's own field order, so that
+// they check the client's decoding rather than mirror it
+type fakeServer struct {
+	t       *testing.T
+	version uint32 // protocol version the server speaks
+
+	info    ServerInfo
+	sinks   []Device
+	sources []Device
+
+	mutex      sync.Mutex
+	authCookie []byte
+	clientName string
+	volumes    map[string][]uint32 // set with SetSinkVolume, by sink name
+	mask       SubscriptionMask
+	conn       net.Conn
+	subscribed chan struct{}
+}
+
+// startFakeServer listens on a socket in a temporary directory and points
+// PULSE_SERVER at it
+func startFakeServer(t *testing.T, version uint32) *fakeServer {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "native")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	t.Setenv("PULSE_SERVER", "unix:"+path)
+	t.Setenv("PULSE_COOKIE", filepath.Join(t.TempDir(), "no-cookie"))
+
+	s := &fakeServer{
+		t:          t,
+		version:    version,
+		volumes:    make(map[string][]uint32),
+		subscribed: make(chan struct{}, 1),
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.conn = conn
+			s.mutex.Unlock()
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	descriptor := make([]byte, 20)
+	for {
+		if _, err := io.ReadFull(conn, descriptor); err != nil {
+			return
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(descriptor))
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return
+		}
+
+		r := &tagReader{data: payload}
+		cmd, err := r.u32()
+		if err != nil {
+			s.t.Error(err)
+			return
+		}
+		tag, err := r.u32()
+		if err != nil {
+			s.t.Error(err)
+			return
+		}
+
+		reply, errCode := s.handle(cmd, r)
+		if errCode != 0 {
+			s.send(conn, new(tagWriter).u32(commandError).u32(tag).u32(errCode))
+			continue
+		}
+
+		packet := new(tagWriter).u32(commandReply).u32(tag)
+		packet.buf.Write(reply.buf.Bytes())
+		s.send(conn, packet)
+
+		if cmd == commandSubscribe {
+			s.subscribed <- struct{}{}
+		}
+	}
+}
+
+// handle returns the reply to a command, or an error code
+func (s *fakeServer) handle(cmd uint32, r *tagReader) (*tagWriter, uint32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	w := new(tagWriter)
+	switch cmd {
+	case commandAuth:
+		if _, err := r.u32(); err != nil {
+			return nil, 3
+		}
+		cookie, err := r.arbitrary()
+		if err != nil {
+			return nil, 3
+		}
+		s.authCookie = cookie
+
+		// the upper bits are flags, which the client must ignore
+		return w.u32(s.version | 0x80000000), 0
+
+	case commandSetClientName:
+		props, err := r.propList()
+		if err != nil {
+			return nil, 3
+		}
+		s.clientName = props["application.name"]
+		return w.u32(7), 0 // client index
+
+	case commandGetServerInfo:
+		w.str(s.info.PackageName).str(s.info.PackageVersion).str("user").str("host")
+		w.sampleSpec()
+		w.str(s.info.DefaultSink).str(s.info.DefaultSource)
+		w.u32(0x1234) // cookie
+		w.channelMap(2)
+		return w, 0
+
+	case commandGetSinkInfo, commandGetSourceInfo:
+		if _, err := r.u32(); err != nil {
+			return nil, 3
+		}
+		name, err := r.str()
+		if err != nil {
+			return nil, 3
+		}
+
+		sink := cmd == commandGetSinkInfo
+		devices, def := s.sources, s.info.DefaultSource
+		if sink {
+			devices, def = s.sinks, s.info.DefaultSink
+		}
+		if name == DefaultSink || name == DefaultSource {
+			name = def
+		}
+		for _, d := range devices {
+			if d.Name == name {
+				return s.writeDevice(w, d, sink), 0
+			}
+		}
+		return nil, 5 // no such entity
+
+	case commandGetSinkInfoList:
+		for _, d := range s.sinks {
+			s.writeDevice(w, d, true)
+		}
+		return w, 0
+
+	case commandGetSourceInfoList:
+		for _, d := range s.sources {
+			s.writeDevice(w, d, false)
+		}
+		return w, 0
+
+	case commandSetSinkVolume:
+		if _, err := r.u32(); err != nil {
+			return nil, 3
+		}
+		name, err := r.str()
+		if err != nil {
+			return nil, 3
+		}
+		volume, err := r.cvolume()
+		if err != nil {
+			return nil, 3
+		}
+		if name == DefaultSink {
+			name = s.info.DefaultSink
+		}
+		s.volumes[name] = volume
+		return w, 0
+
+	case commandSubscribe:
+		mask, err := r.u32()
+		if err != nil {
+			return nil, 3
+		}
+		s.mask = SubscriptionMask(mask)
+		return w, 0
+
+	default:
+		return nil, 2 // unknown command
+	}
+}
+
+// writeDevice writes sink or source info like the server does
+func (s *fakeServer) writeDevice(w *tagWriter, d Device, sink bool) *tagWriter {
+	w.u32(d.Index).str(d.Name).str(d.Description)
+	w.sampleSpec()
+	w.channelMap(len(d.Volume))
+	w.u32(1) // owner module
+	w.cvolume(d.Volume)
+	w.boolean(d.Mute)
+
+	// a sink's monitor source, or the sink a source monitors
+	if sink || d.Monitor {
+		w.u32(d.Index + 100).str(d.Name + ".monitor")
+	} else {
+		w.u32(invalidIndex).str("")
+	}
+
+	w.usec(20000)               // latency
+	w.str("module-alsa-card.c") // driver
+	w.u32(0)                    // flags
+
+	if s.version >= 13 {
+		w.propList(d.Props)
+		w.usec(0) // configured latency
+	}
+
+	if s.version >= 15 {
+		w.volume(VolumeNorm) // base volume
+		w.u32(0)             // state
+		w.u32(65537)         // volume steps
+		w.u32(2)             // card
+	}
+
+	if s.version >= 16 {
+		w.u32(uint32(len(d.Ports)))
+		for _, p := range d.Ports {
+			w.str(p.Name).str(p.Description).u32(p.Priority)
+			if s.version >= 24 {
+				w.u32(p.Available)
+			}
+			if s.version >= 34 {
+				w.str("") // availability group
+				w.u32(uint32(p.Type))
+			}
+		}
+		w.str(d.ActivePort)
+	}
+
+	if (sink && s.version >= 21) || (!sink && s.version >= 22) {
+		w.u8(1)
+		w.formatInfo()
+	}
+
+	return w
+}
+
+// sendEvent sends a subscription event to the client
+func (s *fakeServer) sendEvent(f Facility, t EventType, index uint32) {
+	s.mutex.Lock()
+	conn := s.conn
+	s.mutex.Unlock()
+
+	s.send(conn, new(tagWriter).u32(commandSubscribeEvent).u32(invalidIndex).u32(uint32(f)|uint32(t)).u32(index))
+}
+
+func (s *fakeServer) send(conn net.Conn, packet *tagWriter) {
+	descriptor := make([]byte, 20)
+	binary.BigEndian.PutUint32(descriptor, uint32(packet.buf.Len()))
+	binary.BigEndian.PutUint32(descriptor[4:], commandChannel)
+	if _, err := conn.Write(append(descriptor, packet.buf.Bytes()...)); err != nil {
+		s.t.Error(err)
+	}
+}
+
+// the rest of the tagstruct, which the client only reads
+
+func (w *tagWriter) u8(v uint8) *tagWriter {
+	w.buf.WriteByte(tagU8)
+	w.buf.WriteByte(v)
+	return w
+}
+
+func (w *tagWriter) usec(v uint64) *tagWriter {
+	w.buf.WriteByte(tagUsec)
+	binary.Write(&w.buf, binary.BigEndian, v)
+	return w
+}
+
+func (w *tagWriter) volume(v uint32) *tagWriter {
+	w.buf.WriteByte(tagVolume)
+	binary.Write(&w.buf, binary.BigEndian, v)
+	return w
+}
+
+// sampleSpec writes 16-bit stereo at 48 kHz
+func (w *tagWriter) sampleSpec() *tagWriter {
+	w.buf.WriteByte(tagSampleSpec)
+	w.buf.Write([]byte{3, 2}) // s16le, two channels
+	binary.Write(&w.buf, binary.BigEndian, uint32(48000))
+	return w
+}
+
+func (w *tagWriter) channelMap(channels int) *tagWriter {
+	w.buf.WriteByte(tagChannelMap)
+	w.buf.WriteByte(byte(channels))
+	for i := 0; i < channels; i++ {
+		w.buf.WriteByte(byte(i + 1)) // front left, front right, ...
+	}
+	return w
+}
+
+// formatInfo writes PCM with no properties
+func (w *tagWriter) formatInfo() *tagWriter {
+	w.buf.WriteByte(tagFormatInfo)
+	w.u8(1)
+	return w.propList(nil)
+}
@@ -0,0 +1,299 @@
+package pulse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// tags that prefix each value in a tagstruct
+const (
+	tagString     = 't'
+	tagStringNull = 'N'
+	tagU32        = 'L'
+	tagU8         = 'B'
+	tagU64        = 'R'
+	tagS64        = 'r'
+	tagSampleSpec = 'a'
+	tagArbitrary  = 'x'
+	tagTrue       = '1'
+	tagFalse      = '0'
+	tagTimeval    = 'T'
+	tagUsec       = 'U'
+	tagChannelMap = 'm'
+	tagCVolume    = 'v'
+	tagPropList   = 'P'
+	tagVolume     = 'V'
+	tagFormatInfo = 'f'
+)
+
+var errShortTagstruct = errors.New("pulse: tagstruct ended early")
+
+// tagWriter builds a tagstruct, the serialization format of the native
+// protocol
+type tagWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *tagWriter) u32(v uint32) *tagWriter {
+	w.buf.WriteByte(tagU32)
+	binary.Write(&w.buf, binary.BigEndian, v)
+	return w
+}
+
+func (w *tagWriter) boolean(v bool) *tagWriter {
+	if v {
+		w.buf.WriteByte(tagTrue)
+	} else {
+		w.buf.WriteByte(tagFalse)
+	}
+	return w
+}
+
+// str writes a string. empty strings are written as null strings, which is
+// what the server expects for unset names
+func (w *tagWriter) str(v string) *tagWriter {
+	if v == "" {
+		w.buf.WriteByte(tagStringNull)
+		return w
+	}
+
+	w.buf.WriteByte(tagString)
+	w.buf.WriteString(v)
+	w.buf.WriteByte(0)
+	return w
+}
+
+func (w *tagWriter) arbitrary(v []byte) *tagWriter {
+	w.buf.WriteByte(tagArbitrary)
+	binary.Write(&w.buf, binary.BigEndian, uint32(len(v)))
+	w.buf.Write(v)
+	return w
+}
+
+func (w *tagWriter) cvolume(v []uint32) *tagWriter {
+	w.buf.WriteByte(tagCVolume)
+	w.buf.WriteByte(byte(len(v)))
+	for _, c := range v {
+		binary.Write(&w.buf, binary.BigEndian, c)
+	}
+	return w
+}
+
+func (w *tagWriter) propList(props map[string]string) *tagWriter {
+	w.buf.WriteByte(tagPropList)
+	for k, v := range props {
+		w.str(k)
+
+		// values are arbitrary data; strings keep their null terminator
+		value := append([]byte(v), 0)
+		w.u32(uint32(len(value)))
+		w.arbitrary(value)
+	}
+	w.buf.WriteByte(tagStringNull)
+	return w
+}
+
+// tagReader reads values out of a tagstruct
+type tagReader struct {
+	data []byte
+}
+
+func (r *tagReader) empty() bool {
+	return len(r.data) == 0
+}
+
+func (r *tagReader) take(n int) ([]byte, error) {
+	if len(r.data) < n {
+		return nil, errShortTagstruct
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
+
+func (r *tagReader) tag(want byte) error {
+	b, err := r.take(1)
+	if err != nil {
+		return err
+	}
+	if b[0] != want {
+		return fmt.Errorf("pulse: expected tag %q, got %q", want, b[0])
+	}
+	return nil
+}
+
+func (r *tagReader) u32() (uint32, error) {
+	if err := r.tag(tagU32); err != nil {
+		return 0, err
+	}
+	b, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *tagReader) u8() (uint8, error) {
+	if err := r.tag(tagU8); err != nil {
+		return 0, err
+	}
+	b, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *tagReader) usec() (uint64, error) {
+	if err := r.tag(tagUsec); err != nil {
+		return 0, err
+	}
+	b, err := r.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (r *tagReader) volume() (uint32, error) {
+	if err := r.tag(tagVolume); err != nil {
+		return 0, err
+	}
+	b, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *tagReader) boolean() (bool, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return false, err
+	}
+
+	switch b[0] {
+	case tagTrue:
+		return true, nil
+	case tagFalse:
+		return false, nil
+	default:
+		return false, fmt.Errorf("pulse: expected boolean, got %q", b[0])
+	}
+}
+
+// str reads a string. null strings are read as empty strings
+func (r *tagReader) str() (string, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return "", err
+	}
+
+	switch b[0] {
+	case tagStringNull:
+		return "", nil
+	case tagString:
+		end := bytes.IndexByte(r.data, 0)
+		if end < 0 {
+			return "", errShortTagstruct
+		}
+		s := string(r.data[:end])
+		r.data = r.data[end+1:]
+		return s, nil
+	default:
+		return "", fmt.Errorf("pulse: expected string, got %q", b[0])
+	}
+}
+
+func (r *tagReader) arbitrary() ([]byte, error) {
+	if err := r.tag(tagArbitrary); err != nil {
+		return nil, err
+	}
+	b, err := r.take(4)
+	if err != nil {
+		return nil, err
+	}
+	return r.take(int(binary.BigEndian.Uint32(b)))
+}
+
+// sampleSpec skips a sample spec; we have no use for it
+func (r *tagReader) sampleSpec() error {
+	if err := r.tag(tagSampleSpec); err != nil {
+		return err
+	}
+	_, err := r.take(6) // format, channels, rate
+	return err
+}
+
+// channelMap skips a channel map; we have no use for it
+func (r *tagReader) channelMap() error {
+	if err := r.tag(tagChannelMap); err != nil {
+		return err
+	}
+	b, err := r.take(1)
+	if err != nil {
+		return err
+	}
+	_, err = r.take(int(b[0]))
+	return err
+}
+
+func (r *tagReader) cvolume() ([]uint32, error) {
+	if err := r.tag(tagCVolume); err != nil {
+		return nil, err
+	}
+	b, err := r.take(1)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]uint32, b[0])
+	for i := range volumes {
+		v, err := r.take(4)
+		if err != nil {
+			return nil, err
+		}
+		volumes[i] = binary.BigEndian.Uint32(v)
+	}
+	return volumes, nil
+}
+
+func (r *tagReader) propList() (map[string]string, error) {
+	if err := r.tag(tagPropList); err != nil {
+		return nil, err
+	}
+
+	props := make(map[string]string)
+	for {
+		key, err := r.str()
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return props, nil
+		}
+
+		if _, err := r.u32(); err != nil {
+			return nil, err
+		}
+		value, err := r.arbitrary()
+		if err != nil {
+			return nil, err
+		}
+		props[key] = string(bytes.TrimRight(value, "\x00"))
+	}
+}
+
+// formatInfo skips a format info; we have no use for it
+func (r *tagReader) formatInfo() error {
+	if err := r.tag(tagFormatInfo); err != nil {
+		return err
+	}
+	if _, err := r.u8(); err != nil {
+		return err
+	}
+	_, err := r.propList()
+	return err
+}
@@ -2,12 +2,16 @@ package sbattery
 
 import (
 	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/uevent"
 	"github.com/muni-corn/muse-status/utils"
 	"math"
 	"time"
 
 	"fmt"
+	"path"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 type read struct {
@@ -18,11 +22,30 @@ type read struct {
 
 const maxReads = 40 // used for moving averages
 
+const (
+	pollInterval         = time.Second * 5  // when uevents aren't available
+	fallbackPollInterval = time.Second * 30 // keeps charge rates sampled between uevents
+)
+
+// SecondaryMode decides what the battery block shows as its secondary text
+type SecondaryMode int
+
+// Definitions for SecondaryMode
+const (
+	TimeRemainingMode SecondaryMode = iota // time until full or empty
+	PowerDrawMode                          // watts flowing in or out
+)
+
 // Block is a data block for sbattery
 type Block struct {
+	// guards what Update and updateHealth write, which queries read from
+	// the daemon's connections
+	mutex sync.Mutex
+
 	warningLevel int
 	alarmLevel   int
 
+	supplyDir  string // usually SysPowerSupplyBaseDir
 	battery    string
 	chargeFull int
 
@@ -33,12 +56,33 @@ type Block struct {
 	currentRead                     read
 	lastRead                        read
 
+	health    health
+	powerDraw float32 // in watts
+
+	secondaryMode SecondaryMode
+
+	uevents        <-chan uevent.Event // power_supply changes; nil if unavailable
 	nextUpdateTime time.Time
 }
 
 // NewSmartBatteryBlock returns a new sbattery block
 func NewSmartBatteryBlock(battery string, warningLevel, alarmLevel int) (*Block, error) {
-	b := &Block{battery: battery, warningLevel: warningLevel, alarmLevel: alarmLevel}
+	// without uevents, we fall back to polling
+	uevents, _ := uevent.Listen("power_supply")
+
+	return newSmartBatteryBlock(SysPowerSupplyBaseDir, battery, warningLevel, alarmLevel, uevents)
+}
+
+// newSmartBatteryBlock returns a new sbattery block for a battery in
+// supplyDir, updated on power_supply uevents from uevents (which may be nil)
+func newSmartBatteryBlock(supplyDir, battery string, warningLevel, alarmLevel int, uevents <-chan uevent.Event) (*Block, error) {
+	b := &Block{
+		supplyDir:    supplyDir,
+		battery:      battery,
+		warningLevel: warningLevel,
+		alarmLevel:   alarmLevel,
+		uevents:      uevents,
+	}
 
 	var err error
 	b.chargeFull, err = b.getBatteryChargeMax()
@@ -46,9 +90,17 @@ func NewSmartBatteryBlock(battery string, warningLevel, alarmLevel int) (*Block,
 		return nil, err
 	}
 
+	// health is nice to have, but not required
+	b.updateHealth()
+
 	return b, nil
 }
 
+// SetSecondaryMode sets what the block shows as its secondary text
+func (b *Block) SetSecondaryMode(mode SecondaryMode) {
+	b.secondaryMode = mode
+}
+
 // StartBroadcast starts broadcasting from this block. It returns a channel
 // that sends output when an update should happen
 func (b *Block) StartBroadcast() <-chan bool {
@@ -60,30 +112,95 @@ func (b *Block) StartBroadcast() <-chan bool {
 func (b *Block) broadcast(c chan<- bool) {
 	for {
 		if time.Now().After(b.nextUpdateTime) {
-			// store old values
-			// use percentage for less aggressive updating
-			oldPercentage := b.currentRead.charge / b.chargeFull
-			oldStatus := b.currentRead.status
-
-			b.Update()
-
-			newPercentage := b.currentRead.charge / b.chargeFull
-			if b.currentRead.status != oldStatus || newPercentage != oldPercentage {
-				c <- true
-			}
+			b.updateAndNotify(c)
 		}
 
+		wait := b.nextUpdateTime.Sub(time.Now())
 		if b.getBatteryPercentage() <= b.warningLevel && b.currentRead.status == Discharging {
 			c <- true
-			time.Sleep(time.Second / 15)
-		} else {
-			time.Sleep(b.nextUpdateTime.Sub(time.Now()))
+			wait = time.Second / 15
+		}
+
+		// a nil uevents channel blocks forever, leaving only the timer
+		select {
+		case e, ok := <-b.uevents:
+			if !ok {
+				b.uevents = nil
+				continue
+			}
+			if supplyName(e) == b.battery {
+				b.updateHealth()
+			}
+			if b.affectedBy(e) {
+				b.updateAndNotify(c)
+			}
+		case <-time.After(wait):
 		}
 	}
 }
 
+// affectedBy returns true if a power_supply uevent is about this battery or
+// an adapter that charges it. peripherals' batteries, like a mouse's, change
+// often and have nothing to do with us
+func (b *Block) affectedBy(e uevent.Event) bool {
+	name := supplyName(e)
+	if name == b.battery {
+		return true
+	}
+
+	// the system's adapters are "Mains", or "USB" (and its variants) for
+	// USB-C chargers. older kernels don't send the type, but sysfs has it
+	supplyType, ok := e.Env["POWER_SUPPLY_TYPE"]
+	if !ok {
+		supplyType, _ = utils.GetStringFromFile(b.supplyDir + "/" + name + "/type")
+	}
+	return e.Env["POWER_SUPPLY_SCOPE"] != "Device" && (supplyType == "Mains" || strings.HasPrefix(supplyType, "USB"))
+}
+
+// supplyName returns the name of the power supply a uevent is about
+func supplyName(e uevent.Event) string {
+	if name := e.Env["POWER_SUPPLY_NAME"]; name != "" {
+		return name
+	}
+	return path.Base(e.DevPath)
+}
+
+// updateHealth rereads the battery's health. it only changes when the
+// firmware recalibrates, so it's read when the battery has news, rather than
+// on every update
+func (b *Block) updateHealth() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if h, err := getHealth(b.getBaseDir()); err == nil {
+		b.health = h
+	}
+}
+
+// updateAndNotify updates the block and sends to c if anything visible changed
+func (b *Block) updateAndNotify(c chan<- bool) {
+	// store old values
+	// use percentage for less aggressive updating
+	oldPercentage := b.getBatteryPercentage()
+	oldStatus := b.currentRead.status
+
+	b.Update()
+
+	newPercentage := b.getBatteryPercentage()
+	if b.currentRead.status != oldStatus || newPercentage != oldPercentage {
+		c <- true
+	}
+}
+
 func (b *Block) Update() {
-	b.nextUpdateTime = time.Now().Add(time.Second * 5)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.uevents != nil {
+		b.nextUpdateTime = time.Now().Add(fallbackPollInterval)
+	} else {
+		b.nextUpdateTime = time.Now().Add(pollInterval)
+	}
 
 	// update the max charge, in case it changes, which I'm pretty sure it does tbh
 	// (only update if no error)
@@ -92,6 +209,10 @@ func (b *Block) Update() {
 		b.chargeFull = newChargeFull
 	}
 
+	if p, err := getPowerDraw(b.getBaseDir()); err == nil {
+		b.powerDraw = p
+	}
+
 	newRead, err := b.getNewRead()
 	if err != nil {
 		return
@@ -100,6 +221,11 @@ func (b *Block) Update() {
 	b.currentRead = newRead
 	if b.currentRead != b.lastRead {
 		if b.currentRead.status != b.lastRead.status || b.lastRead.at.IsZero() {
+			// without uevents, a change of status is the best time to
+			// check the health
+			if b.uevents == nil && !b.lastRead.at.IsZero() {
+				b.updateHealth()
+			}
 			b.lastRead = b.currentRead
 		} else if b.currentRead.at.Sub(b.lastRead.at) >= time.Second*5 && b.currentRead.charge-b.lastRead.charge != 0 && (b.currentRead.status == Charging || b.currentRead.status == Discharging) {
 
@@ -154,6 +280,13 @@ const timeFormat = "3:04 pm"
 func (b *Block) Text() (primary, secondary string) {
 	primary = strconv.Itoa(b.getBatteryPercentage()) + "%"
 
+	if b.secondaryMode == PowerDrawMode {
+		if b.currentRead.status == Charging || b.currentRead.status == Discharging {
+			secondary = fmt.Sprintf("%.1f W", b.powerDraw)
+		}
+		return
+	}
+
 	completionTime := b.getCompletionTime()
 	if completionTime.Before(time.Now()) {
 		secondary = ""
@@ -211,6 +344,32 @@ func (b *Block) Output(mode format.Mode) string {
 	return format.FormatClassicBlock(b)
 }
 
+// Query returns the charge and health of the battery
+func (b *Block) Query() map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	info := map[string]interface{}{
+		"battery":      b.battery,
+		"status":       string(b.currentRead.status),
+		"percentage":   b.getBatteryPercentage(),
+		"power_draw_w": b.powerDraw,
+		"full":         b.health.full,
+		"full_design":  b.health.fullDesign,
+		"wear_pct":     b.health.wear(),
+		"cycle_count":  b.health.cycleCount,
+		"manufacturer": b.health.manufacturer,
+		"model_name":   b.health.modelName,
+		"technology":   b.health.technology,
+	}
+
+	if completionTime := b.getCompletionTime(); completionTime.After(time.Now()) {
+		info["completion_time"] = completionTime.Format(timeFormat)
+	}
+
+	return info
+}
+
 func (b *Block) getNewRead() (read, error) {
 	r := read{}
 
@@ -253,7 +412,7 @@ func (b *Block) getBatteryStatus() (ChargeStatus, error) {
 }
 
 func (b *Block) getBaseDir() string {
-	return SysPowerSupplyBaseDir + "/" + b.battery + "/"
+	return b.supplyDir + "/" + b.battery + "/"
 }
 
 func (b *Block) getCompletionTime() time.Time {
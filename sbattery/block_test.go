@@ -0,0 +1,217 @@
+package sbattery
+
+import (
+	"github.com/muni-corn/muse-status/uevent"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeSupplies makes a power_supply directory with a battery, an adapter and
+// a mouse, and returns its path
+func fakeSupplies(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for file, value := range map[string]string{
+		"BAT0/type":               "Battery",
+		"BAT0/status":             "Discharging",
+		"BAT0/charge_now":         "3000000",
+		"BAT0/charge_full":        "4000000",
+		"BAT0/charge_full_design": "5000000",
+		"AC/type":                 "Mains",
+		"AC/online":               "0",
+		"hidpp_battery_0/type":    "Battery",
+		"hidpp_battery_0/scope":   "Device",
+	} {
+		writeSupplyFile(t, dir, file, value)
+	}
+
+	return dir
+}
+
+func writeSupplyFile(t *testing.T, dir, file, value string) {
+	t.Helper()
+
+	path := filepath.Join(dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// supplyEvent returns a change uevent like the kernel sends for a supply
+func supplyEvent(name string, env map[string]string) uevent.Event {
+	devPath := "/devices/LNXSYSTM:00/power_supply/" + name
+	e := uevent.Event{
+		Action:    "change",
+		DevPath:   devPath,
+		Subsystem: "power_supply",
+		Env: map[string]string{
+			"ACTION":            "change",
+			"DEVPATH":           devPath,
+			"SUBSYSTEM":         "power_supply",
+			"POWER_SUPPLY_NAME": name,
+		},
+	}
+	for k, v := range env {
+		e.Env[k] = v
+	}
+	return e
+}
+
+// expectUpdate waits for the block to notify c
+func expectUpdate(t *testing.T, c <-chan bool, what string) {
+	t.Helper()
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatalf("no update after %s", what)
+	}
+}
+
+// expectNoUpdate fails if the block notifies c soon
+func expectNoUpdate(t *testing.T, c <-chan bool, what string) {
+	t.Helper()
+	select {
+	case <-c:
+		t.Fatalf("update after %s", what)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestBatteryEvents(t *testing.T) {
+	dir := fakeSupplies(t)
+	events := make(chan uevent.Event)
+
+	b, err := newSmartBatteryBlock(dir, "BAT0", 10, 5, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := b.StartBroadcast()
+	expectUpdate(t, c, "starting")
+
+	if p := b.getBatteryPercentage(); p != 75 || b.currentRead.status != Discharging {
+		t.Fatalf("battery is %d%%, %s", p, b.currentRead.status)
+	}
+
+	// the battery's charge changes
+	writeSupplyFile(t, dir, "BAT0/charge_now", "2800000")
+	events <- supplyEvent("BAT0", map[string]string{"POWER_SUPPLY_TYPE": "Battery"})
+	expectUpdate(t, c, "a change")
+	if p := b.getBatteryPercentage(); p != 70 {
+		t.Errorf("battery is %d%%, want 70%%", p)
+	}
+
+	// plugging in changes the adapter. older kernels don't send the type,
+	// which has to come from sysfs
+	writeSupplyFile(t, dir, "AC/online", "1")
+	writeSupplyFile(t, dir, "BAT0/status", "Charging")
+	events <- supplyEvent("AC", map[string]string{"POWER_SUPPLY_ONLINE": "1"})
+	expectUpdate(t, c, "plugging in")
+	if b.currentRead.status != Charging {
+		t.Errorf("battery is %s after plugging in", b.currentRead.status)
+	}
+
+	// unplugging
+	writeSupplyFile(t, dir, "AC/online", "0")
+	writeSupplyFile(t, dir, "BAT0/status", "Discharging")
+	events <- supplyEvent("AC", map[string]string{"POWER_SUPPLY_TYPE": "Mains", "POWER_SUPPLY_ONLINE": "0"})
+	expectUpdate(t, c, "unplugging")
+	if b.currentRead.status != Discharging {
+		t.Errorf("battery is %s after unplugging", b.currentRead.status)
+	}
+
+	// a mouse's battery changing doesn't update the block, even though ours
+	// has changed too
+	writeSupplyFile(t, dir, "BAT0/charge_now", "2000000")
+	events <- supplyEvent("hidpp_battery_0", map[string]string{
+		"POWER_SUPPLY_TYPE":     "Battery",
+		"POWER_SUPPLY_SCOPE":    "Device",
+		"POWER_SUPPLY_CAPACITY": "40",
+	})
+	expectNoUpdate(t, c, "a mouse's event")
+	if p := b.getBatteryPercentage(); p != 70 {
+		t.Errorf("battery is %d%% after a mouse's event, want 70%%", p)
+	}
+}
+
+func TestAffectedBy(t *testing.T) {
+	dir := fakeSupplies(t)
+	b, err := newSmartBatteryBlock(dir, "BAT0", 10, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		event uevent.Event
+		want  bool
+	}{
+		{supplyEvent("BAT0", nil), true},
+		{supplyEvent("AC", nil), true},
+		{supplyEvent("ucsi-source-psy-USBC000:001", map[string]string{"POWER_SUPPLY_TYPE": "USB"}), true},
+		{supplyEvent("BAT1", map[string]string{"POWER_SUPPLY_TYPE": "Battery"}), false},
+		{supplyEvent("hidpp_battery_0", nil), false},
+		{supplyEvent("hid-keyboard-battery", map[string]string{"POWER_SUPPLY_TYPE": "USB", "POWER_SUPPLY_SCOPE": "Device"}), false},
+	} {
+		if got := b.affectedBy(test.event); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.event.Env["POWER_SUPPLY_NAME"], got, test.want)
+		}
+	}
+}
+
+func TestHealthUpdates(t *testing.T) {
+	dir := fakeSupplies(t)
+	events := make(chan uevent.Event)
+
+	b, err := newSmartBatteryBlock(dir, "BAT0", 10, 5, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := b.StartBroadcast()
+	expectUpdate(t, c, "starting")
+
+	if w := b.health.wear(); w != 20 {
+		t.Fatalf("wear is %v%%, want 20%%", w)
+	}
+
+	// the adapter's events don't reread the battery's health
+	writeSupplyFile(t, dir, "BAT0/charge_full", "4500000")
+	writeSupplyFile(t, dir, "AC/online", "1")
+	writeSupplyFile(t, dir, "BAT0/status", "Charging")
+	events <- supplyEvent("AC", map[string]string{"POWER_SUPPLY_TYPE": "Mains", "POWER_SUPPLY_ONLINE": "1"})
+	expectUpdate(t, c, "plugging in")
+	if w := b.health.wear(); w != 20 {
+		t.Errorf("wear is %v%% after the adapter's event, want 20%%", w)
+	}
+
+	// but the battery's do
+	writeSupplyFile(t, dir, "BAT0/charge_now", "3500000")
+	events <- supplyEvent("BAT0", map[string]string{"POWER_SUPPLY_TYPE": "Battery"})
+	expectUpdate(t, c, "a change")
+	if w := b.health.wear(); w != 10 {
+		t.Errorf("wear is %v%% after the battery's event, want 10%%", w)
+	}
+}
+
+func TestWear(t *testing.T) {
+	for _, test := range []struct {
+		full, fullDesign int
+		want             float32
+	}{
+		{4000000, 5000000, 20},
+		{5000000, 5000000, 0},
+		{5200000, 5000000, 0}, // new batteries can beat their design
+		{4000000, 0, 0},
+	} {
+		h := health{full: test.full, fullDesign: test.fullDesign}
+		if got := h.wear(); got != test.want {
+			t.Errorf("%d of %d: got %v, want %v", test.full, test.fullDesign, got, test.want)
+		}
+	}
+}
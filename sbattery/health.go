@@ -0,0 +1,83 @@
+package sbattery
+
+import (
+	"github.com/muni-corn/muse-status/utils"
+)
+
+// health describes the condition of a battery, as reported by its firmware
+type health struct {
+	full       int // charge_full or energy_full
+	fullDesign int // charge_full_design or energy_full_design
+	cycleCount int
+
+	manufacturer string
+	modelName    string
+	technology   string
+}
+
+// wear returns how much capacity the battery has lost compared to its design
+// capacity, as a percentage. returns zero if the design capacity is unknown,
+// or if the battery holds more than it was designed to (new batteries often
+// do)
+func (h health) wear() float32 {
+	if h.fullDesign <= 0 || h.full >= h.fullDesign {
+		return 0
+	}
+
+	return 100 - float32(h.full)*100/float32(h.fullDesign)
+}
+
+// getHealth reads health information for the battery in baseDir. only the
+// capacities are required; the rest is left blank if the battery doesn't
+// report it
+func getHealth(baseDir string) (h health, err error) {
+	// compare full and design capacities in the same units. batteries report
+	// either charge (µAh) or energy (µWh)
+	h.full, err = utils.GetIntFromFile(baseDir + "charge_full")
+	if err == nil {
+		h.fullDesign, err = utils.GetIntFromFile(baseDir + "charge_full_design")
+	} else {
+		h.full, err = utils.GetIntFromFile(baseDir + "energy_full")
+		if err == nil {
+			h.fullDesign, err = utils.GetIntFromFile(baseDir + "energy_full_design")
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	h.cycleCount, _ = utils.GetIntFromFile(baseDir + "cycle_count")
+	h.manufacturer, _ = utils.GetStringFromFile(baseDir + "manufacturer")
+	h.modelName, _ = utils.GetStringFromFile(baseDir + "model_name")
+	h.technology, _ = utils.GetStringFromFile(baseDir + "technology")
+
+	return
+}
+
+// getPowerDraw returns the power flowing into or out of the battery, in watts
+func getPowerDraw(baseDir string) (float32, error) {
+	// power_now is in µW
+	power, err := utils.GetIntFromFile(baseDir + "power_now")
+	if err == nil {
+		return float32(abs(power)) / 1e6, nil
+	}
+
+	// some batteries only report current (µA) and voltage (µV)
+	current, err := utils.GetIntFromFile(baseDir + "current_now")
+	if err != nil {
+		return 0, err
+	}
+	voltage, err := utils.GetIntFromFile(baseDir + "voltage_now")
+	if err != nil {
+		return 0, err
+	}
+
+	return float32(abs(current)) / 1e6 * float32(voltage) / 1e6, nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,274 @@
+package sbattery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/uevent"
+	"github.com/muni-corn/muse-status/utils"
+)
+
+const peripheralPollInterval = time.Minute
+
+// capacity levels reported by devices that don't know their exact charge
+const (
+	levelCritical = "Critical"
+	levelLow      = "Low"
+)
+
+// rough percentages for devices that only report a capacity_level
+var capacityLevelPercentages = map[string]int{
+	"Critical": 5,
+	"Low":      15,
+	"Normal":   50,
+	"High":     80,
+	"Full":     100,
+}
+
+// peripheral is a battery-powered device, like a wireless mouse, keyboard or
+// headset
+type peripheral struct {
+	name       string
+	supply     string // directory name under SysPowerSupplyBaseDir
+	percentage int
+	level      string // capacity_level, if the device doesn't report capacity
+	status     ChargeStatus
+}
+
+// text returns the device name with its charge
+func (p peripheral) text() string {
+	if p.level != "" {
+		return p.name + " " + p.level
+	}
+	return fmt.Sprintf("%s %d%%", p.name, p.percentage)
+}
+
+// PeripheralBlock shows peripheral devices with low batteries. It's hidden
+// when every device is fine
+type PeripheralBlock struct {
+	warningLevel int
+	alarmLevel   int
+
+	mutex   sync.Mutex // guards devices and low, which queries read
+	devices []peripheral
+	low     []peripheral // sorted from lowest charge to highest
+
+	lastText  string
+	lastAlarm bool
+
+	uevents <-chan uevent.Event
+}
+
+// NewPeripheralBlock returns a new PeripheralBlock. devices at or below
+// warningLevel are shown
+func NewPeripheralBlock(warningLevel, alarmLevel int) *PeripheralBlock {
+	b := &PeripheralBlock{
+		warningLevel: warningLevel,
+		alarmLevel:   alarmLevel,
+	}
+
+	// without uevents, we only poll
+	b.uevents, _ = uevent.Listen("power_supply")
+
+	return b
+}
+
+// StartBroadcast starts broadcasting from this block. It returns a channel
+// that sends output when an update should happen
+func (b *PeripheralBlock) StartBroadcast() <-chan bool {
+	c := make(chan bool)
+	go b.broadcast(c)
+	return c
+}
+
+func (b *PeripheralBlock) broadcast(c chan<- bool) {
+	// init
+	b.Update()
+	b.shouldNotify()
+	c <- true
+
+	for {
+		select {
+		case _, ok := <-b.uevents:
+			if !ok {
+				b.uevents = nil
+			}
+		case <-time.After(peripheralPollInterval):
+		}
+
+		// every supply's uevents wake us, but few of them change anything
+		// we show
+		b.Update()
+		if b.shouldNotify() {
+			c <- true
+		}
+	}
+}
+
+func (b *PeripheralBlock) shouldNotify() bool {
+	primary, _ := b.Text()
+	alarm := b.alarmed()
+	if primary != b.lastText || alarm != b.lastAlarm {
+		b.lastText, b.lastAlarm = primary, alarm
+		return true
+	}
+	return false
+}
+
+// Update rescans the peripherals
+func (b *PeripheralBlock) Update() {
+	devices, err := getPeripherals()
+	if err != nil {
+		return
+	}
+
+	var low []peripheral
+	for _, d := range devices {
+		if b.isLow(d) {
+			low = append(low, d)
+		}
+	}
+
+	sort.Slice(low, func(i, j int) bool {
+		return low[i].percentage < low[j].percentage
+	})
+
+	b.mutex.Lock()
+	b.devices = devices
+	b.low = low
+	b.mutex.Unlock()
+}
+
+func (b *PeripheralBlock) isLow(p peripheral) bool {
+	if p.status == Charging || p.status == Full {
+		return false
+	}
+	// a level's rough percentage isn't worth comparing; the level says
+	// whether it's low
+	if p.level != "" {
+		return p.level == levelLow || p.level == levelCritical
+	}
+	return p.percentage <= b.warningLevel
+}
+
+// Name returns "peripherals"
+func (b *PeripheralBlock) Name() string {
+	return "peripherals"
+}
+
+// Icon returns a battery icon for the lowest device
+func (b *PeripheralBlock) Icon() rune {
+	if len(b.low) == 0 {
+		return ' '
+	}
+	return getBatteryIcon(Discharging, b.low[0].percentage)
+}
+
+// Text lists every low device as primary text
+func (b *PeripheralBlock) Text() (primary, secondary string) {
+	texts := make([]string, len(b.low))
+	for i, p := range b.low {
+		texts[i] = p.text()
+	}
+	return strings.Join(texts, ", "), ""
+}
+
+// Colorer returns the alarm colorer if any device is critically low, or the
+// warning colorer otherwise
+func (b *PeripheralBlock) Colorer() format.Colorer {
+	if b.alarmed() {
+		return format.GetAlarmColorer()
+	}
+	return format.GetWarningColorer()
+}
+
+// alarmed returns true if any device is critically low
+func (b *PeripheralBlock) alarmed() bool {
+	for _, p := range b.low {
+		if p.level == levelCritical || p.level == "" && p.percentage <= b.alarmLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// Hidden returns true if no device is low
+func (b *PeripheralBlock) Hidden() bool {
+	return len(b.low) == 0
+}
+
+// ForceShort returns false
+func (b *PeripheralBlock) ForceShort() bool {
+	return false
+}
+
+func (b *PeripheralBlock) Output(mode format.Mode) string {
+	return format.FormatClassicBlock(b)
+}
+
+// Query returns the charge of every peripheral
+func (b *PeripheralBlock) Query() map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	devices := make([]map[string]interface{}, len(b.devices))
+	for i, d := range b.devices {
+		devices[i] = map[string]interface{}{
+			"name":       d.name,
+			"supply":     d.supply,
+			"percentage": d.percentage,
+			"level":      d.level,
+			"status":     string(d.status),
+			"low":        b.isLow(d),
+		}
+	}
+
+	return map[string]interface{}{
+		"devices": devices,
+	}
+}
+
+// getPeripherals returns every power supply with scope=Device that reports
+// its charge
+func getPeripherals() ([]peripheral, error) {
+	infos, err := ioutil.ReadDir(SysPowerSupplyBaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []peripheral
+	for _, info := range infos {
+		dir := SysPowerSupplyBaseDir + "/" + info.Name() + "/"
+
+		scope, err := utils.GetStringFromFile(dir + "scope")
+		if err != nil || scope != "Device" {
+			continue
+		}
+
+		p := peripheral{supply: info.Name()}
+		if p.percentage, err = utils.GetIntFromFile(dir + "capacity"); err != nil {
+			level, err := utils.GetStringFromFile(dir + "capacity_level")
+			percentage, ok := capacityLevelPercentages[level]
+			if err != nil || !ok {
+				continue
+			}
+			p.level, p.percentage = level, percentage
+		}
+
+		if p.name, err = utils.GetStringFromFile(dir + "model_name"); err != nil || p.name == "" {
+			p.name = info.Name()
+		}
+
+		str, _ := utils.GetStringFromFile(dir + "status")
+		p.status = ChargeStatus(str)
+
+		devices = append(devices, p)
+	}
+
+	return devices, nil
+}
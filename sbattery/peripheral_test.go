@@ -0,0 +1,33 @@
+package sbattery
+
+import "testing"
+
+func TestPeripheralIsLow(t *testing.T) {
+	b := &PeripheralBlock{warningLevel: 60, alarmLevel: 50}
+
+	for _, test := range []struct {
+		name       string
+		device     peripheral
+		low, alarm bool
+	}{
+		{"above warning", peripheral{percentage: 70, status: Discharging}, false, false},
+		{"warning", peripheral{percentage: 55, status: Discharging}, true, false},
+		{"alarm", peripheral{percentage: 40, status: Discharging}, true, true},
+		{"charging", peripheral{percentage: 40, status: Charging}, false, false},
+		{"normal level", peripheral{level: "Normal", percentage: 50, status: Discharging}, false, false},
+		{"low level", peripheral{level: levelLow, percentage: 15, status: Discharging}, true, false},
+		{"critical level", peripheral{level: levelCritical, percentage: 5, status: Discharging}, true, true},
+	} {
+		if got := b.isLow(test.device); got != test.low {
+			t.Errorf("%s: low is %v, want %v", test.name, got, test.low)
+		}
+
+		b.low = nil
+		if test.low {
+			b.low = []peripheral{test.device}
+		}
+		if got := b.alarmed(); got != test.alarm {
+			t.Errorf("%s: alarmed is %v, want %v", test.name, got, test.alarm)
+		}
+	}
+}
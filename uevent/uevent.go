@@ -0,0 +1,92 @@
+// Package uevent listens for kernel uevents, which the kernel broadcasts over
+// netlink whenever a device is added, removed or changed
+package uevent
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelGroup is the netlink multicast group the kernel sends uevents to
+// (udev re-broadcasts them on group 2)
+const kernelGroup = 1
+
+// Event is a single kernel uevent
+type Event struct {
+	Action    string // e.g. "add", "remove" or "change"
+	DevPath   string
+	Subsystem string
+
+	// Env holds every KEY=value pair in the event, including the ones above
+	Env map[string]string
+}
+
+// Listen returns a channel that receives every uevent for the given
+// subsystem, such as "power_supply" or "backlight". If subsystem is empty,
+// all uevents are sent. The channel is closed if the socket fails
+func Listen(subsystem string) (<-chan Event, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: kernelGroup,
+	})
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	c := make(chan Event)
+	go func() {
+		defer close(c)
+		defer unix.Close(fd)
+
+		buf := make([]byte, 1<<16)
+		for {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err == unix.EINTR || err == unix.ENOBUFS {
+				continue
+			} else if err != nil {
+				return
+			}
+
+			e, ok := parse(buf[:n])
+			if !ok || (subsystem != "" && e.Subsystem != subsystem) {
+				continue
+			}
+
+			c <- e
+		}
+	}()
+
+	return c, nil
+}
+
+// parse parses a raw uevent, which looks like
+// "change@/devices/...\0ACTION=change\0DEVPATH=/devices/...\0SUBSYSTEM=...\0"
+func parse(msg []byte) (e Event, ok bool) {
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) == 0 || !bytes.Contains(fields[0], []byte("@")) {
+		return e, false
+	}
+
+	e.Env = make(map[string]string)
+	for _, f := range fields[1:] {
+		kv := strings.SplitN(string(f), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		e.Env[kv[0]] = kv[1]
+	}
+
+	e.Action = e.Env["ACTION"]
+	e.DevPath = e.Env["DEVPATH"]
+	e.Subsystem = e.Env["SUBSYSTEM"]
+
+	return e, e.Action != ""
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// WatchFiles returns a channel that receives whenever any of the files at
+// paths are modified, and a func that stops watching. Paths that can't be
+// watched are skipped; an error is only returned if none of them can be. The
+// channel is closed once watching stops, whether by stop or by failing later
+// on. Changes that arrive before the last one was received are merged with it
+func WatchFiles(paths ...string) (changes <-chan struct{}, stop func(), err error) {
+	// non-blocking, so the runtime polls it and closing it ends a read
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	watched := 0
+	for _, p := range paths {
+		var watchErr error
+		if _, watchErr = unix.InotifyAddWatch(fd, p, unix.IN_MODIFY|unix.IN_CLOSE_WRITE); watchErr == nil {
+			watched++
+		} else {
+			err = watchErr
+		}
+	}
+	if watched == 0 {
+		unix.Close(fd)
+		return nil, nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "inotify")
+	c := make(chan struct{}, 1)
+	go func() {
+		defer close(c)
+
+		// we don't care what the events are, only that they happened, so
+		// every read is one notification. a watched file going away sends
+		// one last event, then nothing until we're stopped
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			if n, err := f.Read(buf); err != nil || n <= 0 {
+				return
+			}
+
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return c, func() { f.Close() }, nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brightness")
+	if err := ioutil.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, stop, err := WatchFiles(path, filepath.Join(filepath.Dir(path), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("no change after writing")
+	}
+
+	// the file going away, like a device being unplugged, doesn't end the
+	// watch by itself; stopping does
+	os.Remove(path)
+	stop()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("changes weren't closed after stopping")
+		}
+	}
+}
+
+func TestWatchFilesNone(t *testing.T) {
+	if _, _, err := WatchFiles(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error without any files to watch")
+	}
+}
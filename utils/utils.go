@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // GetIntFromFile returns a number in a file
@@ -23,3 +24,14 @@ func GetStringFromFile(filepath string) (value string, err error) {
 	}
 	return
 }
+
+// Capitalize capitalizes the first letter of s
+func Capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -1,67 +1,322 @@
 package volume
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/pulse"
+)
+
+// reconnectInterval is how often we try to reach the PulseAudio server after
+// losing it
+const reconnectInterval = time.Second * 5
+
+const (
+	defaultStep      = 5
+	defaultMaxVolume = 100
+	nextSinkAction   = "muse-status volume next-sink"
 )
 
 type Block struct {
 	lastVolume    int
 	currentVolume int
+	lastMuted     bool
+	muted         bool
+	lastSink      string
+	sink          pulse.Device // the default sink; empty without a server
 	rapidfire     bool
 
+	mutex         sync.Mutex    // guards pulse, which commands use too
+	pulse         *pulseBackend // nil if no server is available
+	fallback      backend
+	nextReconnect time.Time
+
+	step      int // percentage points for inc and dec
+	maxVolume int // inc and set won't go past this percentage
+
 	fader *format.FadingColorer
+	wake  chan struct{} // wakes the broadcast after commands
 }
 
+// NewVolumeBlock returns a new volume.Block. Volume changes are pushed by the
+// PulseAudio server; rapidfire only enables polling for when there isn't one
 func NewVolumeBlock(rapidfire bool) *Block {
-	if rapidfire {
-		// println("WARNING! A volume block has been created with rapidfire enabled. This can be VERY bad for your system's performance. Try using `muse-status notify volume` instead after volume updates.")
-	}
-
 	b := &Block{
 		rapidfire: rapidfire,
+		fallback:  amixerBackend{},
+		step:      defaultStep,
+		maxVolume: defaultMaxVolume,
+		wake:      make(chan struct{}, 1),
 	}
 	b.fader = &format.FadingColorer{
 		Duration:   3,
 		StartColor: format.PrimaryColor(),
 		EndColor:   format.SecondaryColor(),
 	}
+
+	b.pulse, _ = newPulseBackend()
+
 	return b
 }
 
+// SetStep sets how many percentage points `volume inc` and `volume dec`
+// change the volume by, if no amount is given
+func (b *Block) SetStep(step int) {
+	b.step = step
+}
+
+// SetMaxVolume caps the volume that commands can set, as a percentage. It may
+// be more than 100 to allow boosting
+func (b *Block) SetMaxVolume(max int) {
+	b.maxVolume = max
+}
+
 func (b *Block) StartBroadcast() <-chan bool {
 	c := make(chan bool)
-	if b.rapidfire {
-		go b.broadcast(c)
-	}
+	go b.broadcast(c)
 	return c
 }
 
 func (b *Block) broadcast(c chan<- bool) {
 	// init
 	b.Update()
+	b.lastVolume, b.lastMuted, b.lastSink = b.currentVolume, b.muted, b.sinkKey()
 	c <- true
 
-	for b.rapidfire {
-		b.Update()
-
+	for {
+		// only tick while animating, while waiting to reconnect, or if
+		// we're polling
+		var tick <-chan time.Time
 		if b.fader.IsFading() {
-			c <- true
+			tick = time.After(time.Second / 15)
+		} else if b.pulse == nil && b.rapidfire {
+			tick = time.After(time.Second / 10)
+		} else if b.pulse == nil {
+			tick = time.After(reconnectInterval)
 		}
-		if b.currentVolume != b.lastVolume {
-			c <- true
-			b.lastVolume = b.currentVolume
+
+		select {
+		case _, ok := <-b.pulseEvents():
+			if !ok {
+				b.pulse.client.Close()
+				b.setPulse(nil)
+				continue
+			}
+		case <-b.wake:
+		case <-tick:
+			if b.pulse == nil && time.Now().After(b.nextReconnect) {
+				b.nextReconnect = time.Now().Add(reconnectInterval)
+				p, _ := newPulseBackend()
+				b.setPulse(p)
+			}
+		}
+
+		b.Update()
+		if b.currentVolume != b.lastVolume || b.muted != b.lastMuted || b.sinkKey() != b.lastSink {
+			b.lastVolume, b.lastMuted, b.lastSink = b.currentVolume, b.muted, b.sinkKey()
 			b.fader.Trigger()
+			c <- true
+		} else if b.fader.IsFading() {
+			c <- true
 		}
+	}
+}
+
+// currentPulse returns the PulseAudio backend, or nil if there is no server.
+// Only the broadcast changes it, but commands and queries come from other
+// goroutines, so they should keep the pointer they get from here
+func (b *Block) currentPulse() *pulseBackend {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.pulse
+}
 
-		time.Sleep(time.Second / 10)
+func (b *Block) setPulse(p *pulseBackend) {
+	b.mutex.Lock()
+	b.pulse = p
+	b.mutex.Unlock()
+}
+
+// pulseEvents returns the server's events, or nil (which blocks forever) if
+// there is no server
+func (b *Block) pulseEvents() <-chan pulse.Event {
+	if b.pulse == nil {
+		return nil
 	}
+	return b.pulse.events
 }
 
 func (b *Block) Update() {
-	b.currentVolume, _ = getCurrentVolume()
+	if p := b.currentPulse(); p != nil {
+		sink, err := p.sink("")
+		if err == nil {
+			b.sink = sink
+			b.currentVolume, b.muted = sink.VolumePercent(), sink.Mute
+		}
+		return
+	}
+
+	volume, muted, err := b.fallback.volume("")
+	if err != nil {
+		return
+	}
+
+	b.sink = pulse.Device{}
+	b.currentVolume, b.muted = volume, muted
+}
+
+// sinkKey identifies the default sink and its active port, so we can tell
+// when either changes
+func (b *Block) sinkKey() string {
+	return b.sink.Name + "/" + b.sink.ActivePort
+}
+
+// backend returns the PulseAudio backend if we're connected, or the fallback
+// otherwise
+func (b *Block) backend() backend {
+	if p := b.currentPulse(); p != nil {
+		return p
+	}
+	return b.fallback
+}
+
+// HandleCommand handles volume commands:
+//
+//	set <percent> [sink]
+//	inc|dec [percent] [sink]
+//	mute|unmute|toggle [sink]
+//	sink <sink>
+//	next-sink
+//
+// Commands apply to the default sink unless one is named. `sink` and
+// `next-sink` change the default sink
+func (b *Block) HandleCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: volume set|inc|dec|mute|unmute|toggle [percent] [sink], or volume sink <sink>|next-sink")
+	}
+
+	cmd, args := args[0], args[1:]
+
+	if cmd == "sink" || cmd == "next-sink" {
+		return b.switchSink(cmd, args)
+	}
+
+	// an amount is optional for inc and dec, so anything that isn't a number
+	// is the sink
+	amount := -1
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(strings.TrimSuffix(args[0], "%")); err == nil {
+			amount = n
+			args = args[1:]
+		}
+	}
+
+	var sink string
+	if len(args) > 0 {
+		sink = args[0]
+	}
+
+	be := b.backend()
+	var err error
+	switch cmd {
+	case "set", "inc", "dec":
+		err = b.changeVolume(be, cmd, amount, sink)
+	case "mute":
+		err = be.setMute(sink, true)
+	case "unmute":
+		err = be.setMute(sink, false)
+	case "toggle":
+		var muted bool
+		if _, muted, err = be.volume(sink); err == nil {
+			err = be.setMute(sink, !muted)
+		}
+	default:
+		return fmt.Errorf("unknown volume command: %s", cmd)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (b *Block) switchSink(cmd string, args []string) error {
+	p := b.currentPulse()
+	if p == nil {
+		return errors.New("switching sinks needs a PulseAudio server")
+	}
+
+	var name string
+	if cmd == "sink" {
+		if len(args) < 1 {
+			return errors.New("usage: volume sink <sink>")
+		}
+		name = args[0]
+	} else {
+		var err error
+		if name, err = p.nextSink(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.client.SetDefaultSink(name); err != nil {
+		return err
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (b *Block) changeVolume(be backend, cmd string, amount int, sink string) error {
+	if amount < 0 {
+		if cmd == "set" {
+			return errors.New("usage: volume set <percent> [sink]")
+		}
+		amount = b.step
+	}
+
+	current, _, err := be.volume(sink)
+	if err != nil {
+		return err
+	}
+
+	target := amount
+	switch cmd {
+	case "inc":
+		target = current + amount
+	case "dec":
+		target = current - amount
+	}
+
+	if target > b.maxVolume {
+		// increasing shouldn't pull down a volume that was already boosted
+		// past the cap by something else
+		if cmd == "inc" && current > b.maxVolume {
+			target = current
+		} else {
+			target = b.maxVolume
+		}
+	}
+	if target < 0 {
+		target = 0
+	}
+
+	return be.setVolume(sink, target)
 }
 
 func (b *Block) Name() string {
@@ -69,15 +324,57 @@ func (b *Block) Name() string {
 }
 
 func (b *Block) Icon() rune {
+	if b.muted {
+		return muteIcon
+	}
+	if icon, ok := outputIcons[getOutputKind(b.sink)]; ok {
+		return icon
+	}
 	return getIcon(b.currentVolume)
 }
 
+// Text returns the volume as primary, and the output device as secondary
 func (b *Block) Text() (primary, secondary string) {
-	if b.currentVolume > 0 {
-		return fmt.Sprintf("%d%%", b.currentVolume), ""
+	if port, ok := b.sink.Port(); ok && getOutputKind(b.sink) != bluetoothOutput {
+		secondary = port.Description
 	} else {
-		return "Muted", ""
+		secondary = b.sink.Description
 	}
+
+	if b.muted || b.currentVolume <= 0 {
+		return "Muted", secondary
+	}
+	return fmt.Sprintf("%d%%", b.currentVolume), secondary
+}
+
+// Query returns the volume and the default sink, along with every other sink
+func (b *Block) Query() map[string]interface{} {
+	info := map[string]interface{}{
+		"volume": b.currentVolume,
+		"muted":  b.muted,
+	}
+
+	p := b.currentPulse()
+	if p == nil {
+		return info
+	}
+
+	info["sink"] = b.sink.Name
+	info["description"] = b.sink.Description
+	info["output"] = outputKindNames[getOutputKind(b.sink)]
+	if port, ok := b.sink.Port(); ok {
+		info["port"] = port.Description
+	}
+
+	if sinks, err := p.client.Sinks(); err == nil {
+		names := make([]string, len(sinks))
+		for i, s := range sinks {
+			names[i] = s.Name
+		}
+		info["sinks"] = names
+	}
+
+	return info
 }
 
 func (b *Block) Colorer() format.Colorer {
@@ -92,6 +389,7 @@ func (b *Block) ForceShort() bool {
 	return false
 }
 
+// Output returns the block, clickable to cycle sinks
 func (b *Block) Output(mode format.Mode) string {
-	return format.FormatClassicBlock(b)
+	return format.Action(nextSinkAction, format.FormatClassicBlock(b))
 }
@@ -2,12 +2,44 @@ package volume
 
 import (
 	// "github.com/muni-corn/muse-status/format"
+	"errors"
 	"os/exec"
 	"regexp"
 	"strconv"
+	"strings"
 	// "time"
+
+	"github.com/muni-corn/muse-status/pulse"
 )
 
+// outputKind is the kind of device a sink plays through
+type outputKind int
+
+// Definitions for outputKind
+const (
+	speakerOutput outputKind = iota
+	headphoneOutput
+	headsetOutput
+	hdmiOutput
+	bluetoothOutput
+)
+
+var outputKindNames = map[outputKind]string{
+	speakerOutput:   "speakers",
+	headphoneOutput: "headphones",
+	headsetOutput:   "headset",
+	hdmiOutput:      "hdmi",
+	bluetoothOutput: "bluetooth",
+}
+
+// icons for outputs other than speakers, which show the volume level instead
+var outputIcons = map[outputKind]rune{
+	headphoneOutput: '\uf2cb',
+	headsetOutput:   '\uf2ce',
+	hdmiOutput:      '\uf502',
+	bluetoothOutput: '\uf0b0', // bluetooth-audio
+}
+
 var (
 	volumeIcons     = [3]rune{'', '', ''}
 	muteIcon        = ''
@@ -70,9 +102,27 @@ var (
 // 	return channel
 // } // }}}
 
-// returns the current volume percentage as an int, or zero
-// if muted
-func getCurrentVolume() (percentage int, err error) {
+// backend reads and changes the volume of an output. an empty sink name
+// means the default output
+type backend interface {
+	// volume returns the volume as a percentage, and whether it's muted
+	volume(sink string) (percentage int, muted bool, err error)
+	setVolume(sink string, percentage int) error
+	setMute(sink string, muted bool) error
+}
+
+var errNamedSink = errors.New("named sinks need a PulseAudio server")
+
+// amixerBackend uses the ALSA Master control. It's used when no PulseAudio
+// server is available
+type amixerBackend struct{}
+
+func (amixerBackend) volume(sink string) (percentage int, muted bool, err error) {
+	if sink != "" {
+		err = errNamedSink
+		return
+	}
+
 	output, err := exec.Command("amixer", "sget", "Master").Output()
 	if err != nil {
 		return
@@ -80,21 +130,178 @@ func getCurrentVolume() (percentage int, err error) {
 
 	strOutput := string(output)
 
-	mixerStatus := onOffRegex.FindStringSubmatch(strOutput)[1] // should be 'on' or 'off'. if it's not, then wtf
-	if mixerStatus == "off" {
-		percentage = 0 // muted
-	} else if mixerStatus == "on" {
-		percentageStr := percentageRegex.FindStringSubmatch(strOutput)[1]
-		percentage, err = strconv.Atoi(percentageStr)
+	statusMatch := onOffRegex.FindStringSubmatch(strOutput) // should be 'on' or 'off'
+	percentageMatch := percentageRegex.FindStringSubmatch(strOutput)
+	if statusMatch == nil || percentageMatch == nil {
+		err = errors.New("couldn't parse amixer output")
+		return
 	}
+
+	muted = statusMatch[1] == "off"
+	percentage, err = strconv.Atoi(percentageMatch[1])
 	return
 }
 
+func (amixerBackend) setVolume(sink string, percentage int) error {
+	if sink != "" {
+		return errNamedSink
+	}
+	return exec.Command("amixer", "-q", "sset", "Master", strconv.Itoa(percentage)+"%").Run()
+}
+
+func (amixerBackend) setMute(sink string, muted bool) error {
+	if sink != "" {
+		return errNamedSink
+	}
+
+	state := "unmute"
+	if muted {
+		state = "mute"
+	}
+	return exec.Command("amixer", "-q", "sset", "Master", state).Run()
+}
+
+// pulseBackend uses a PulseAudio (or pipewire-pulse) server, which also tells
+// us when the volume changes
+type pulseBackend struct {
+	client *pulse.Client
+	events <-chan pulse.Event
+}
+
+func newPulseBackend() (*pulseBackend, error) {
+	client, err := pulse.Dial("muse-status")
+	if err != nil {
+		return nil, err
+	}
+
+	// server events tell us when the default sink changes
+	events, err := client.Subscribe(pulse.SubscribeSink | pulse.SubscribeServer)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &pulseBackend{client: client, events: events}, nil
+}
+
+// sink returns the named sink, or the default one if sink is empty
+func (p *pulseBackend) sink(sink string) (pulse.Device, error) {
+	return p.client.Sink(pulseSinkName(sink))
+}
+
+// nextSink returns the name of the sink after the default one, wrapping
+// around to the first
+func (p *pulseBackend) nextSink() (string, error) {
+	sinks, err := p.client.Sinks()
+	if err != nil {
+		return "", err
+	}
+	if len(sinks) == 0 {
+		return "", errors.New("no sinks found")
+	}
+
+	current, err := p.sink("")
+	if err != nil {
+		return "", err
+	}
+
+	for i, s := range sinks {
+		if s.Name == current.Name {
+			return sinks[(i+1)%len(sinks)].Name, nil
+		}
+	}
+	return sinks[0].Name, nil
+}
+
+func (p *pulseBackend) volume(sink string) (percentage int, muted bool, err error) {
+	s, err := p.client.Sink(pulseSinkName(sink))
+	if err != nil {
+		return
+	}
+
+	return s.VolumePercent(), s.Mute, nil
+}
+
+func (p *pulseBackend) setVolume(sink string, percentage int) error {
+	s, err := p.client.Sink(pulseSinkName(sink))
+	if err != nil {
+		return err
+	}
+
+	// scale every channel to keep the balance between them
+	var loudest uint64
+	for _, v := range s.Volume {
+		if uint64(v) > loudest {
+			loudest = uint64(v)
+		}
+	}
+
+	target := uint64(percentage) * pulse.VolumeNorm / 100
+	volume := make([]uint32, len(s.Volume))
+	for i, v := range s.Volume {
+		if loudest == 0 {
+			volume[i] = uint32(target)
+		} else {
+			volume[i] = uint32(uint64(v) * target / loudest)
+		}
+	}
+
+	return p.client.SetSinkVolume(s.Name, volume)
+}
+
+func (p *pulseBackend) setMute(sink string, muted bool) error {
+	return p.client.SetSinkMute(pulseSinkName(sink), muted)
+}
+
+func pulseSinkName(sink string) string {
+	if sink == "" {
+		return pulse.DefaultSink
+	}
+	return sink
+}
+
+// getOutputKind decides what kind of device a sink plays through, from its
+// port type if the server knows it, or from its names otherwise
+func getOutputKind(sink pulse.Device) outputKind {
+	if sink.Props["device.bus"] == "bluetooth" {
+		return bluetoothOutput
+	}
+
+	port, _ := sink.Port()
+	switch port.Type {
+	case pulse.PortHeadphones:
+		return headphoneOutput
+	case pulse.PortHeadset, pulse.PortHandsfree:
+		return headsetOutput
+	case pulse.PortHDMI, pulse.PortTV, pulse.PortVideo:
+		return hdmiOutput
+	case pulse.PortBluetooth:
+		return bluetoothOutput
+	case pulse.PortUnknown:
+		name := strings.ToLower(port.Name)
+		switch {
+		case strings.Contains(name, "headphone"):
+			return headphoneOutput
+		case strings.Contains(name, "headset"):
+			return headsetOutput
+		case strings.Contains(name, "hdmi"):
+			return hdmiOutput
+		}
+	}
+
+	return speakerOutput
+}
+
 func getIcon(percentage int) rune {
 	if percentage <= 0 {
 		return muteIcon
 	}
 
+	// boosted volumes get the loudest icon
+	if percentage > 100 {
+		percentage = 100
+	}
+
 	index := percentage * len(volumeIcons) / 100
 
 	// constrain index (should never go below zero)
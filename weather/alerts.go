@@ -0,0 +1,170 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Severity is how dangerous an alert is, from the Common Alerting Protocol
+type Severity int
+
+// Definitions for Severity, from least to most severe
+const (
+	UnknownSeverity Severity = iota
+	Minor
+	Moderate
+	Severe
+	Extreme
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Minor:
+		return "minor"
+	case Moderate:
+		return "moderate"
+	case Severe:
+		return "severe"
+	case Extreme:
+		return "extreme"
+	default:
+		return "unknown"
+	}
+}
+
+// parseSeverity parses a CAP severity, like "Severe"
+func parseSeverity(s string) Severity {
+	switch strings.ToLower(s) {
+	case "minor":
+		return Minor
+	case "moderate":
+		return Moderate
+	case "severe":
+		return Severe
+	case "extreme":
+		return Extreme
+	default:
+		return UnknownSeverity
+	}
+}
+
+// Alert is a weather alert, like a tornado warning
+type Alert struct {
+	ID          string
+	Event       string // like "Tornado Warning"
+	Severity    Severity
+	Headline    string
+	Description string
+	Instruction string
+	Sender      string
+	Start       time.Time
+	End         time.Time // zero if the alert lasts until it's cancelled
+}
+
+// active returns true if the alert is in effect at t
+func (a Alert) active(t time.Time) bool {
+	return !t.Before(a.Start) && (a.End.IsZero() || t.Before(a.End))
+}
+
+// text returns the alert's event, and when it ends, like "Tornado Warning
+// until 9:45 pm"
+func (a Alert) text() string {
+	if a.End.IsZero() {
+		return a.Event
+	}
+	return a.Event + " until " + a.End.Format("3:04 pm")
+}
+
+// AlertProvider fetches weather alerts. Providers that supply alerts
+// implement it too
+type AlertProvider interface {
+	Name() string
+
+	// Alerts returns the alerts at loc
+	Alerts(loc *WeatherLocation) ([]Alert, error)
+}
+
+// NewAlertProvider returns the alert provider with the given name: "nws" (the
+// US National Weather Service) or "openweathermap", which needs an API key
+// with a One Call subscription
+func NewAlertProvider(name, key string) (AlertProvider, error) {
+	switch name {
+	case "nws":
+		return newNWS(), nil
+	case "openweathermap", "owm":
+		if key == "" {
+			return nil, fmt.Errorf("openweathermap needs an API key")
+		}
+		return newOpenWeatherMap(key), nil
+	default:
+		return nil, fmt.Errorf("unknown weather alert provider: %s", name)
+	}
+}
+
+// mostSevereAlert returns the most severe of the alerts active at t. of
+// alerts that are as severe as each other, the first is returned
+func mostSevereAlert(alerts []Alert, t time.Time) (Alert, bool) {
+	var (
+		worst Alert
+		found bool
+	)
+	for _, a := range alerts {
+		if a.active(t) && (!found || a.Severity > worst.Severity) {
+			worst, found = a, true
+		}
+	}
+	return worst, found
+}
+
+// formatAlert returns an alert as a few lines of text, for `details`
+func formatAlert(a Alert) string {
+	lines := []string{strings.ToUpper(a.text())}
+	for _, s := range []string{a.Headline, a.Description, a.Instruction} {
+		if s = strings.TrimSpace(s); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+const (
+	notificationsService = "org.freedesktop.Notifications"
+	notificationsPath    = "/org/freedesktop/Notifications"
+
+	// notification urgencies
+	normalUrgency   = byte(1)
+	criticalUrgency = byte(2)
+)
+
+// sendAlertNotification shows a desktop notification for an alert
+func sendAlertNotification(a Alert) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+
+	urgency := normalUrgency
+	if a.Severity >= Severe {
+		urgency = criticalUrgency
+	}
+
+	body := a.Headline
+	if body == "" {
+		body = a.Description
+	}
+
+	return conn.Object(notificationsService, notificationsPath).Call(
+		notificationsService+".Notify", 0,
+		"muse-status",
+		uint32(0),
+		"weather-severe-alert",
+		a.text(),
+		body,
+		[]string{},
+		map[string]dbus.Variant{"urgency": dbus.MakeVariant(urgency)},
+		int32(-1),
+	).Err
+}
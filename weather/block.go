@@ -2,21 +2,132 @@ package weather
 
 import (
 	"github.com/muni-corn/muse-status/format"
+	"github.com/muni-corn/muse-status/utils"
 
+	"fmt"
 	"time"
 )
 
+const (
+	updateInterval   = time.Minute * 20
+	minRetryInterval = time.Second * 30 // after the first failure, doubling after each one
+	staleAfter       = time.Hour        // reports older than this are dimmed
+	relocateInterval = time.Hour        // how long a found location is trusted
+)
+
 type Block struct {
-	currentReport fullWeatherReport
+	provider Provider         // nil if it couldn't be set up
+	report   *Report          // nil until a report is fetched
+	reported *WeatherLocation // where report is for
+	fetched  time.Time        // when report was fetched
+	err      error            // from the last fetch
+	failures int              // in a row, for backing off
+
+	providerErr error // why there's no provider
+
+	alertProvider AlertProvider   // nil for no alerts
+	alertErr      error           // from the last fetch of alerts
+	notified      map[string]bool // alerts that have been notified, by ID
 
-	location *WeatherLocation
+	refresh <-chan struct{} // fetches right away when notified
+
+	location      *WeatherLocation
+	located       time.Time // when we last looked for location
+	fixedLocation bool      // given, rather than found
+	city          string    // to look up by name
+	ipLookup      bool      // whether our IP address may be used to find us
+
+	units           Units
+	primaryFields   []string
+	secondaryFields []string
 }
 
+// NewWeatherBlock returns a new weather.Block for loc. If loc is nil, the
+// location is found with GeoClue, or by a city name if one is set. Reports
+// come from Open-Meteo unless another provider is set
 func NewWeatherBlock(loc *WeatherLocation) *Block {
-	if loc == nil {
-		loc, _ = getLocation()
+	b := &Block{
+		location:        loc,
+		fixedLocation:   loc != nil,
+		provider:        newOpenMeteo(),
+		primaryFields:   []string{"temperature"},
+		secondaryFields: []string{"description"},
+		notified:        make(map[string]bool),
 	}
-	return &Block{location: loc}
+
+	// show the last report until we get a new one, if it's for here. a
+	// location we have to find is checked once it's found. its alerts were
+	// notified before the restart
+	var cached cachedReport
+	if err := readCache(reportCacheFile, &cached); err == nil && cached.Report != nil && (loc == nil || sameArea(loc, cached.Location)) {
+		b.report, b.reported, b.fetched = cached.Report, cached.Location, cached.Fetched
+		for _, a := range b.report.Alerts {
+			b.notified[a.ID] = true
+		}
+	}
+
+	return b
+}
+
+// SetCity sets a city to report the weather for, looked up by name
+func (b *Block) SetCity(city string) {
+	b.city = city
+	if !b.fixedLocation {
+		b.location = nil
+	}
+}
+
+// SetIPLookup sets whether the location may be found from our IP address if
+// GeoClue can't find it. This tells a third party where we are, and is wrong
+// on a VPN
+func (b *Block) SetIPLookup(allowed bool) {
+	b.ipLookup = allowed
+}
+
+// RefreshOn makes the block fetch a new report whenever c is notified, such
+// as when the network comes back
+func (b *Block) RefreshOn(c <-chan struct{}) {
+	b.refresh = c
+}
+
+// SetUnits sets the units that weather is shown in
+func (b *Block) SetUnits(u Units) {
+	b.units = u
+}
+
+// SetFields sets which fields are shown as primary and secondary text. Fields
+// are "temperature", "feels-like", "humidity", "wind", "description",
+// "precipitation" (like "Rain at 3 pm", only if it's likely soon) and "sun"
+// (the next sunrise or sunset, like "Sunset at 7:42 pm")
+func (b *Block) SetFields(primary, secondary []string) error {
+	if err := checkFields(primary); err != nil {
+		return err
+	}
+	if err := checkFields(secondary); err != nil {
+		return err
+	}
+
+	b.primaryFields, b.secondaryFields = primary, secondary
+	return nil
+}
+
+// SetProvider sets the service that reports come from. Alerts are separate,
+// and off unless SetAlertProvider is called
+func (b *Block) SetProvider(p Provider) {
+	b.provider, b.providerErr = p, nil
+}
+
+// SetProviderError leaves the block without a provider, showing err instead
+// of the weather. It's for when the chosen provider can't be set up, like
+// OpenWeatherMap without a key; falling back to another would hide the mistake
+func (b *Block) SetProviderError(err error) {
+	b.provider, b.alertProvider, b.providerErr = nil, nil, err
+	b.report, b.err = nil, err
+}
+
+// SetAlertProvider sets the service that alerts come from, or nil for none
+func (b *Block) SetAlertProvider(p AlertProvider) {
+	b.alertProvider, b.alertErr = p, nil
 }
 
 func (b *Block) StartBroadcast() <-chan bool {
@@ -26,15 +137,153 @@ func (b *Block) StartBroadcast() <-chan bool {
 }
 
 func (b *Block) broadcast(c chan<- bool) {
+	fetch := true
 	for {
-		b.Update()
+		if fetch {
+			b.Update()
+		}
 		c <- true
-		time.Sleep(time.Minute * 20)
+
+		fetch = b.wait()
+	}
+}
+
+// wait waits until it's time to fetch again, or for a refresh, and returns
+// true. at sunrise or sunset, it returns false so the block can be redrawn
+// without fetching
+func (b *Block) wait() bool {
+	timer := time.After(b.nextUpdate())
+
+	var sunEvent <-chan time.Time
+	if t, _, ok := nextSunEvent(b.report, time.Now()); ok {
+		sunEvent = time.After(time.Until(t))
+	}
+
+	for {
+		select {
+		case <-timer:
+			return true
+		case <-sunEvent:
+			return false
+		case <-b.refresh:
+			// the network coming up right after a fetch (like at startup)
+			// doesn't need another one
+			if b.failures > 0 || time.Since(b.fetched) >= minRetryInterval {
+				// a new network may mean we've moved
+				b.located = time.Time{}
+				return true
+			}
+		}
 	}
 }
 
+// Daytime returns true if the sun is up where the weather is reported for, so
+// other parts of the daemon (like theme switching) can follow the sun without
+// asking for the weather again. ok is false if there isn't a report yet
+func (b *Block) Daytime() (day, ok bool) {
+	return isDaytime(b.report, time.Now())
+}
+
+// nextUpdate returns how long to wait before fetching again. failures are
+// retried sooner, backing off exponentially
+func (b *Block) nextUpdate() time.Duration {
+	if b.failures == 0 {
+		return updateInterval
+	}
+
+	wait := minRetryInterval
+	for i := 1; i < b.failures && wait < updateInterval; i++ {
+		wait *= 2
+	}
+	if wait > updateInterval {
+		wait = updateInterval
+	}
+	return wait
+}
+
 func (b *Block) Update() {
-	b.currentReport, _ = getFullWeatherReport(b.location)
+	if b.provider == nil {
+		b.err = b.providerErr
+		return
+	}
+
+	// we may have moved since we last looked; if we can't tell, the last
+	// location is still the best guess. GeoClue can take a while to answer,
+	// so it isn't asked every update
+	if !b.fixedLocation && (b.location == nil || b.city == "" && time.Since(b.located) >= relocateInterval) {
+		b.located = time.Now()
+		loc, err := b.locate()
+		if err == nil {
+			b.location = loc
+		} else if b.location == nil {
+			b.err = err
+			b.failures++
+			return
+		}
+	}
+
+	// a report for somewhere else is worse than none, like after moving or
+	// changing the city
+	if b.report != nil && !sameArea(b.reported, b.location) {
+		b.report, b.reported = nil, nil
+	}
+
+	report, err := b.provider.Report(b.location)
+	b.err = err
+	if err != nil {
+		b.failures++
+		return
+	}
+
+	// alerts failing doesn't spoil the rest of the report. the last ones we
+	// got are the best guess
+	if b.alertProvider != nil {
+		report.Alerts, b.alertErr = b.alertProvider.Alerts(b.location)
+		if isAuthError(b.alertErr) {
+			// like a key without a subscription that includes alerts. the
+			// error stays for queries
+			b.alertProvider = nil
+		}
+		if b.alertErr != nil && b.report != nil {
+			report.Alerts = b.report.Alerts
+		}
+	}
+
+	b.report, b.reported, b.fetched, b.failures = report, b.location, time.Now(), 0
+	b.notifyAlerts()
+	writeCache(reportCacheFile, cachedReport{
+		Fetched:  b.fetched,
+		Provider: b.provider.Name(),
+		Location: b.location,
+		Report:   report,
+	})
+}
+
+// notifyAlerts sends a desktop notification for each active alert that
+// hasn't had one yet
+func (b *Block) notifyAlerts() {
+	now := time.Now()
+	for _, a := range b.report.Alerts {
+		if b.notified[a.ID] || !a.active(now) {
+			continue
+		}
+		if err := sendAlertNotification(a); err == nil {
+			b.notified[a.ID] = true
+		}
+	}
+}
+
+// alert returns the most severe active alert
+func (b *Block) alert() (Alert, bool) {
+	if b.report == nil {
+		return Alert{}, false
+	}
+	return mostSevereAlert(b.report.Alerts, time.Now())
+}
+
+// stale returns true if the report is too old to trust
+func (b *Block) stale() bool {
+	return time.Since(b.fetched) > staleAfter
 }
 
 func (b *Block) Name() string {
@@ -53,14 +302,159 @@ func (b *Block) Output(mode format.Mode) string {
 	return format.FormatClassicBlock(b)
 }
 
+// Text returns the fields chosen for primary and secondary text, or the error
+// that's keeping us from them. The most severe alert, if any, replaces the
+// secondary text
 func (b *Block) Text() (primary, secondary string) {
-	return getTemperatureString(b.currentReport), getWeatherDescription(b.currentReport)
+	if b.report == nil && b.err != nil {
+		return "No weather", b.err.Error()
+	}
+
+	primary = formatFields(b.report, b.units, b.primaryFields)
+	if a, ok := b.alert(); ok {
+		return primary, a.text()
+	}
+	return primary, formatFields(b.report, b.units, b.secondaryFields)
 }
 
 func (b *Block) Icon() rune {
-	return getWeatherIcon(b.currentReport)
+	return getWeatherIcon(b.report)
 }
 
+// Colorer returns the alarm colorer for a severe alert, the warning colorer
+// for a lesser one, and the dim colorer if there isn't a recent report
 func (b *Block) Colorer() format.Colorer {
+	if a, ok := b.alert(); ok {
+		if a.Severity >= Severe {
+			return format.GetAlarmColorer()
+		}
+		return format.GetWarningColorer()
+	}
+	if b.report == nil || b.stale() {
+		return format.GetDimColorer()
+	}
 	return format.GetDefaultColorer()
 }
+
+// Query returns the current conditions and forecast
+func (b *Block) Query() map[string]interface{} {
+	info := map[string]interface{}{}
+	if b.provider != nil {
+		info["provider"] = b.provider.Name()
+	}
+	if b.err != nil {
+		info["error"] = b.err.Error()
+	}
+	if b.alertProvider != nil {
+		info["alert_provider"] = b.alertProvider.Name()
+	}
+	if b.alertErr != nil {
+		info["alert_error"] = b.alertErr.Error()
+	}
+	if b.location != nil {
+		info["latitude"] = b.location.Latitude
+		info["longitude"] = b.location.Longitude
+		if b.location.Name != "" {
+			info["location"] = b.location.Name
+		}
+	}
+	if b.report == nil {
+		return info
+	}
+
+	info["fetched"] = b.fetched.Format(time.RFC3339)
+	info["stale"] = b.stale()
+
+	c := b.report.Current
+	info["temperature"] = b.units.formatTemperature(c.Temperature)
+	info["feels_like"] = b.units.formatTemperature(c.FeelsLike)
+	info["wind"] = b.units.formatSpeed(c.WindSpeed) + " " + compassPoint(c.WindDeg)
+	info["temperature_c"] = c.Temperature
+	info["feels_like_c"] = c.FeelsLike
+	info["humidity"] = c.Humidity
+	info["wind_speed_ms"] = c.WindSpeed
+	info["wind_deg"] = c.WindDeg
+	info["description"] = c.Description
+	info["time"] = c.Time.Format(time.RFC3339)
+
+	if text := precipitationText(b.report); text != "" {
+		info["precipitation"] = utils.Capitalize(text)
+	}
+
+	if day, ok := b.Daytime(); ok {
+		info["daytime"] = day
+	}
+	if sunrise, sunset, ok := sunTimes(b.report, time.Now()); ok {
+		info["sunrise"] = sunrise.Format(time.RFC3339)
+		info["sunset"] = sunset.Format(time.RFC3339)
+	}
+
+	hours := make([]map[string]interface{}, len(b.report.Hourly))
+	for i, h := range b.report.Hourly {
+		hours[i] = map[string]interface{}{
+			"time":                 h.Time.Format(time.RFC3339),
+			"temperature_c":        h.Temperature,
+			"precipitation_chance": h.PrecipitationChance,
+			"precipitation_mm":     h.Precipitation,
+			"description":          h.Description,
+		}
+	}
+	info["hourly"] = hours
+
+	days := make([]map[string]interface{}, len(b.report.Daily))
+	for i, d := range b.report.Daily {
+		days[i] = map[string]interface{}{
+			"date":                 d.Date.Format("2006-01-02"),
+			"high_c":               d.High,
+			"low_c":                d.Low,
+			"precipitation_chance": d.PrecipitationChance,
+			"description":          d.Description,
+		}
+	}
+	info["daily"] = days
+
+	now := time.Now()
+	alerts := make([]map[string]interface{}, 0, len(b.report.Alerts))
+	for _, a := range b.report.Alerts {
+		alert := map[string]interface{}{
+			"id":          a.ID,
+			"event":       a.Event,
+			"severity":    a.Severity.String(),
+			"headline":    a.Headline,
+			"description": a.Description,
+			"instruction": a.Instruction,
+			"sender":      a.Sender,
+			"start":       a.Start.Format(time.RFC3339),
+			"active":      a.active(now),
+		}
+		if !a.End.IsZero() {
+			alert["end"] = a.End.Format(time.RFC3339)
+		}
+		alerts = append(alerts, alert)
+	}
+	info["alerts"] = alerts
+
+	return info
+}
+
+// Details returns the current conditions and forecast as text
+func (b *Block) Details() string {
+	if b.report == nil {
+		if b.err != nil {
+			return "No weather: " + b.err.Error()
+		}
+		return "No weather yet"
+	}
+
+	details := formatForecast(b.report, b.units)
+	if a, ok := b.alert(); ok {
+		details = formatAlert(a) + "\n\n" + details
+	}
+	if b.location != nil && b.location.Name != "" {
+		details = b.location.Name + "\n" + details
+	}
+	if b.stale() {
+		details = fmt.Sprintf("As of %s:\n%s", b.fetched.Format("Mon 3:04 pm"), details)
+	}
+	return details
+}
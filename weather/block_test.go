@@ -0,0 +1,166 @@
+package weather
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestBlock returns a block with its cache in a temporary directory
+func newTestBlock(t *testing.T, loc *WeatherLocation) *Block {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	return NewWeatherBlock(loc)
+}
+
+func TestProviderError(t *testing.T) {
+	b := newTestBlock(t, testLocation)
+	_, err := NewProvider("openweathermap", "")
+	if err == nil {
+		t.Fatal("expected an error for openweathermap without a key")
+	}
+	b.SetProviderError(err)
+	b.Update()
+
+	if primary, secondary := b.Text(); primary != "No weather" || secondary != err.Error() {
+		t.Errorf("text is %q, %q", primary, secondary)
+	}
+
+	info := b.Query()
+	if info["error"] != err.Error() {
+		t.Errorf("query error is %v", info["error"])
+	}
+	if _, ok := info["provider"]; ok {
+		t.Errorf("query has provider %v", info["provider"])
+	}
+
+	// setting a provider clears the error
+	b.SetProvider(failingProvider{errors.New("offline")})
+	b.Update()
+	if _, secondary := b.Text(); secondary != "offline" {
+		t.Errorf("secondary text is %q", secondary)
+	}
+}
+
+// failingProvider is a provider that always fails
+type failingProvider struct {
+	err error
+}
+
+func (failingProvider) Name() string {
+	return "failing"
+}
+
+func (p failingProvider) Report(loc *WeatherLocation) (*Report, error) {
+	return nil, p.err
+}
+
+func TestAlertsOptIn(t *testing.T) {
+	b := newTestBlock(t, testLocation)
+
+	// openweathermap can supply alerts, but they need a subscription, so
+	// they're only used if asked for
+	b.SetProvider(newOpenWeatherMap("key"))
+	if b.alertProvider != nil {
+		t.Errorf("alerts are from %s without asking", b.alertProvider.Name())
+	}
+}
+
+func TestAlertsAuthError(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"cod":401,"message":"Invalid API key"}`))
+	}))
+	defer srv.Close()
+
+	alerts := newOpenWeatherMap("key")
+	alerts.baseURL = srv.URL
+
+	b := newTestBlock(t, testLocation)
+	b.SetProvider(staticProvider{&Report{}})
+	b.SetAlertProvider(alerts)
+
+	b.Update()
+	b.Update()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("alerts were requested %d times, want once", n)
+	}
+
+	info := b.Query()
+	if info["alert_error"] != "openweathermap: invalid api key" {
+		t.Errorf("query alert error is %v", info["alert_error"])
+	}
+	if _, ok := info["alert_provider"]; ok {
+		t.Errorf("query has alert provider %v", info["alert_provider"])
+	}
+	if info["error"] != nil {
+		t.Errorf("query error is %v", info["error"])
+	}
+}
+
+// staticProvider is a provider that always reports the same
+type staticProvider struct {
+	report *Report
+}
+
+func (staticProvider) Name() string {
+	return "static"
+}
+
+func (p staticProvider) Report(loc *WeatherLocation) (*Report, error) {
+	r := *p.report
+	return &r, nil
+}
+
+func TestCachedReportLocation(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	here := &WeatherLocation{Latitude: 52.52, Longitude: 13.41}
+	nearby := &WeatherLocation{Latitude: 52.55, Longitude: 13.38}
+	elsewhere := &WeatherLocation{Latitude: 48.14, Longitude: 11.58}
+
+	b := NewWeatherBlock(here)
+	b.SetProvider(staticProvider{&Report{Current: Conditions{Description: "clear sky"}}})
+	b.Update()
+	if b.report == nil {
+		t.Fatal("no report")
+	}
+
+	for _, test := range []struct {
+		name string
+		loc  *WeatherLocation
+		want bool
+	}{
+		{"here", here, true},
+		{"nearby", nearby, true},
+		{"elsewhere", elsewhere, false},
+		{"to be found", nil, true},
+	} {
+		b := NewWeatherBlock(test.loc)
+		if got := b.report != nil; got != test.want {
+			t.Errorf("%s: cached report used is %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// a report from before locations were cached isn't used for a given one
+	writeCache(reportCacheFile, cachedReport{Report: &Report{}})
+	if b := NewWeatherBlock(here); b.report != nil {
+		t.Error("cached report without a location is used")
+	}
+}
+
+func TestReportForElsewhere(t *testing.T) {
+	b := newTestBlock(t, testLocation)
+	b.report = &Report{Current: Conditions{Description: "snow"}}
+	b.reported = &WeatherLocation{Latitude: -33.87, Longitude: 151.21}
+
+	// the report is dropped even though a new one can't be fetched
+	b.SetProvider(failingProvider{errors.New("offline")})
+	b.Update()
+	if b.report != nil {
+		t.Errorf("report for elsewhere is kept: %+v", b.report.Current)
+	}
+}
@@ -0,0 +1,79 @@
+package weather
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	reportCacheFile = "weather.json"
+	sameAreaDegrees = 0.1 // about 10 km
+)
+
+// cachedReport is the last good report, saved so that it can be shown right
+// away after a restart
+type cachedReport struct {
+	Fetched  time.Time        `json:"fetched"`
+	Provider string           `json:"provider"`
+	Location *WeatherLocation `json:"location"` // where the report is for
+	Report   *Report          `json:"report"`
+}
+
+// sameArea returns true if two locations are close enough to share a report.
+// found locations wander a little from one fix to the next
+func sameArea(a, b *WeatherLocation) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return math.Abs(float64(a.Latitude-b.Latitude)) < sameAreaDegrees && math.Abs(float64(a.Longitude-b.Longitude)) < sameAreaDegrees
+}
+
+// cachePath returns the path of a file in muse-status's cache directory
+func cachePath(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "muse-status", name), nil
+}
+
+// readCache decodes a cached file into v
+func readCache(name string, v interface{}) error {
+	path, err := cachePath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeCache encodes v into a cached file. the file is replaced atomically,
+// so a crash can't leave half of it behind
+func writeCache(name string, v interface{}) error {
+	path, err := cachePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
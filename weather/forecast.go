@@ -0,0 +1,89 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muni-corn/muse-status/utils"
+)
+
+const (
+	precipitationWindow = time.Hour * 6 // how far ahead to warn about precipitation
+	likelyChance        = 50            // percent
+	likelyAmount        = 0.5           // mm, for hours without a chance
+)
+
+// nextPrecipitation returns the first forecast hour within the window that
+// precipitation is likely in
+func nextPrecipitation(r *Report) (HourForecast, bool) {
+	now := time.Now()
+	for _, h := range r.Hourly {
+		if h.Time.After(now.Add(precipitationWindow)) {
+			break
+		}
+		if h.PrecipitationChance >= likelyChance || h.Precipitation >= likelyAmount {
+			return h, true
+		}
+	}
+	return HourForecast{}, false
+}
+
+// precipitationText returns text like "rain at 3 pm" if precipitation is
+// likely soon, or an empty string otherwise
+func precipitationText(r *Report) string {
+	h, ok := nextPrecipitation(r)
+	if !ok {
+		return ""
+	}
+
+	kind := precipitationKind(h.Icon)
+	if !h.Time.After(time.Now()) {
+		return kind + " now"
+	}
+	return kind + " at " + h.Time.Format("3 pm")
+}
+
+// precipitationKind returns what falls in conditions with the given icon
+func precipitationKind(icon string) string {
+	switch {
+	case strings.HasPrefix(icon, "13"):
+		return "snow"
+	case strings.HasPrefix(icon, "11"):
+		return "thunderstorms"
+	default:
+		return "rain"
+	}
+}
+
+// formatForecast returns a report as a few lines of text, for `details`
+func formatForecast(r *Report, u Units) string {
+	c := r.Current
+	lines := []string{
+		fmt.Sprintf("Now: %s, %s (feels like %s, %d%% humidity, wind %s %s)",
+			u.formatTemperature(c.Temperature), c.Description, u.formatTemperature(c.FeelsLike),
+			c.Humidity, u.formatSpeed(c.WindSpeed), compassPoint(c.WindDeg)),
+	}
+
+	if text := precipitationText(r); text != "" {
+		lines = append(lines, utils.Capitalize(text))
+	}
+
+	if len(r.Hourly) > 0 {
+		lines = append(lines, "", "Hourly:")
+		for _, h := range r.Hourly {
+			lines = append(lines, fmt.Sprintf("  %5s  %5s  %3d%%  %s",
+				h.Time.Format("3 pm"), u.formatTemperature(h.Temperature), h.PrecipitationChance, h.Description))
+		}
+	}
+
+	if len(r.Daily) > 0 {
+		lines = append(lines, "", "Daily:")
+		for _, d := range r.Daily {
+			lines = append(lines, fmt.Sprintf("  %s  %5s / %-5s  %3d%%  %s",
+				d.Date.Format("Mon"), u.formatTemperature(d.High), u.formatTemperature(d.Low), d.PrecipitationChance, d.Description))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
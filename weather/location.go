@@ -0,0 +1,205 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	locationCacheFile = "location.json"
+
+	geocodingURLTemplate = "https://geocoding-api.open-meteo.com/v1/search?count=1&name=%s"
+	ipLocationURL        = "http://ip-api.com/json/?fields=status,message,lat,lon,city,country"
+
+	geoClueService      = "org.freedesktop.GeoClue2"
+	geoClueManagerPath  = "/org/freedesktop/GeoClue2/Manager"
+	geoClueTimeout      = time.Second * 30
+	geoClueAccuracyCity = uint32(4) // GCLUE_ACCURACY_LEVEL_CITY
+)
+
+// cachedLocation is the last location we found. city is set if it was
+// found by name
+type cachedLocation struct {
+	City     string           `json:"city,omitempty"`
+	Location *WeatherLocation `json:"location"`
+}
+
+// locate finds the location to report the weather for. a city is looked up
+// by name; otherwise GeoClue is asked, then our IP address if allowed. if
+// everything fails, the last location we found is used
+func (b *Block) locate() (*WeatherLocation, error) {
+	var cached cachedLocation
+	cacheErr := readCache(locationCacheFile, &cached)
+
+	var (
+		loc *WeatherLocation
+		err error
+	)
+	if b.city != "" {
+		// cities don't move
+		if cacheErr == nil && cached.City == b.city && cached.Location != nil {
+			return cached.Location, nil
+		}
+		loc, err = locateCity(b.city)
+	} else {
+		loc, err = locateGeoClue()
+		if err != nil && b.ipLookup {
+			loc, err = locateIP()
+		}
+	}
+
+	if err != nil {
+		if cacheErr == nil && cached.City == b.city && cached.Location != nil {
+			return cached.Location, nil
+		}
+		return nil, fmt.Errorf("couldn't find the location: %v", err)
+	}
+
+	writeCache(locationCacheFile, cachedLocation{City: b.city, Location: loc})
+	return loc, nil
+}
+
+// locateCity looks up a city by name with Open-Meteo's geocoding
+func locateCity(city string) (*WeatherLocation, error) {
+	var res struct {
+		Results []struct {
+			Name      string  `json:"name"`
+			Latitude  float32 `json:"latitude"`
+			Longitude float32 `json:"longitude"`
+			Admin1    string  `json:"admin1"`
+			Country   string  `json:"country"`
+		} `json:"results"`
+	}
+	if err := getJSON(httpClient, "geocoding", fmt.Sprintf(geocodingURLTemplate, url.QueryEscape(city)), &res); err != nil {
+		return nil, err
+	}
+	if len(res.Results) == 0 {
+		return nil, fmt.Errorf("geocoding: no place called %s", city)
+	}
+
+	r := res.Results[0]
+	return &WeatherLocation{
+		Latitude:  r.Latitude,
+		Longitude: r.Longitude,
+		Name:      joinNonEmpty(r.Name, r.Country),
+	}, nil
+}
+
+// locateGeoClue asks GeoClue for our location. GeoClue decides how (Wi-Fi
+// networks, GPS, or its own IP lookup), and may ask the user for permission
+func locateGeoClue() (*WeatherLocation, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var clientPath dbus.ObjectPath
+	manager := conn.Object(geoClueService, geoClueManagerPath)
+	if err := manager.Call(geoClueService+".Manager.GetClient", 0).Store(&clientPath); err != nil {
+		return nil, err
+	}
+
+	client := conn.Object(geoClueService, clientPath)
+	if err := client.SetProperty(geoClueService+".Client.DesktopId", dbus.MakeVariant("muse-status")); err != nil {
+		return nil, err
+	}
+	if err := client.SetProperty(geoClueService+".Client.RequestedAccuracyLevel", dbus.MakeVariant(geoClueAccuracyCity)); err != nil {
+		return nil, err
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(clientPath),
+		dbus.WithMatchInterface(geoClueService+".Client"),
+		dbus.WithMatchMember("LocationUpdated"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+
+	if err := client.Call(geoClueService+".Client.Start", 0).Err; err != nil {
+		return nil, err
+	}
+	defer client.Call(geoClueService+".Client.Stop", 0)
+
+	var locationPath dbus.ObjectPath
+	select {
+	case s := <-signals:
+		// LocationUpdated(old, new)
+		if len(s.Body) < 2 {
+			return nil, errors.New("geoclue: bad LocationUpdated signal")
+		}
+		var ok bool
+		if locationPath, ok = s.Body[1].(dbus.ObjectPath); !ok {
+			return nil, errors.New("geoclue: bad LocationUpdated signal")
+		}
+	case <-time.After(geoClueTimeout):
+		return nil, errors.New("geoclue: timed out")
+	}
+
+	location := conn.Object(geoClueService, locationPath)
+	lat, err := location.GetProperty(geoClueService + ".Location.Latitude")
+	if err != nil {
+		return nil, err
+	}
+	lon, err := location.GetProperty(geoClueService + ".Location.Longitude")
+	if err != nil {
+		return nil, err
+	}
+
+	loc := &WeatherLocation{}
+	if v, ok := lat.Value().(float64); ok {
+		loc.Latitude = float32(v)
+	}
+	if v, ok := lon.Value().(float64); ok {
+		loc.Longitude = float32(v)
+	}
+	if desc, err := location.GetProperty(geoClueService + ".Location.Description"); err == nil {
+		loc.Name, _ = desc.Value().(string)
+	}
+
+	return loc, nil
+}
+
+// locateIP looks up the location of our IP address. this tells ip-api.com
+// who we are, and is wrong on a VPN, so it's only used if allowed
+func locateIP() (*WeatherLocation, error) {
+	var res struct {
+		Status  string  `json:"status"`
+		Message string  `json:"message"`
+		Lat     float32 `json:"lat"`
+		Lon     float32 `json:"lon"`
+		City    string  `json:"city"`
+		Country string  `json:"country"`
+	}
+	if err := getJSON(httpClient, "ip-api", ipLocationURL, &res); err != nil {
+		return nil, err
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("ip-api: %s", res.Message)
+	}
+
+	return &WeatherLocation{
+		Latitude:  res.Lat,
+		Longitude: res.Lon,
+		Name:      joinNonEmpty(res.City, res.Country),
+	}, nil
+}
+
+// joinNonEmpty joins the parts that aren't empty with commas
+func joinNonEmpty(parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
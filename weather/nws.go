@@ -0,0 +1,80 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	nwsBaseURL           = "https://api.weather.gov"
+	nwsAlertsURLTemplate = "%s/alerts/active?point=%.4f,%.4f"
+)
+
+// nws gets alerts from the US National Weather Service. it only knows about
+// the US
+type nws struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newNWS() nws {
+	return nws{baseURL: nwsBaseURL, client: httpClient}
+}
+
+func (nws) Name() string {
+	return "nws"
+}
+
+func (p nws) Alerts(loc *WeatherLocation) ([]Alert, error) {
+	var res struct {
+		Features []struct {
+			Properties struct {
+				ID          string    `json:"id"`
+				Event       string    `json:"event"`
+				Severity    string    `json:"severity"`
+				Headline    string    `json:"headline"`
+				Description string    `json:"description"`
+				Instruction string    `json:"instruction"`
+				SenderName  string    `json:"senderName"`
+				Effective   time.Time `json:"effective"`
+				Onset       time.Time `json:"onset"`
+				Ends        time.Time `json:"ends"`
+				Expires     time.Time `json:"expires"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := getJSON(p.client, p.Name(), fmt.Sprintf(nwsAlertsURLTemplate, p.baseURL, loc.Latitude, loc.Longitude), &res); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]Alert, 0, len(res.Features))
+	for _, f := range res.Features {
+		a := f.Properties
+
+		// alerts are in effect from when they're issued, even if the weather
+		// hasn't arrived yet
+		start := a.Effective
+		if start.IsZero() {
+			start = a.Onset
+		}
+		end := a.Ends
+		if end.IsZero() {
+			end = a.Expires
+		}
+
+		alerts = append(alerts, Alert{
+			ID:          a.ID,
+			Event:       a.Event,
+			Severity:    parseSeverity(a.Severity),
+			Headline:    a.Headline,
+			Description: a.Description,
+			Instruction: a.Instruction,
+			Sender:      a.SenderName,
+			Start:       start,
+			End:         end,
+		})
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,44 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNWSAlerts(t *testing.T) {
+	srv := serveTestdata(t, map[string]string{"/alerts/active": "nws_alerts.json"})
+	p := nws{baseURL: srv.URL, client: srv.Client()}
+
+	alerts, err := p.Alerts(testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("%d alerts, want 2", len(alerts))
+	}
+
+	a := alerts[0]
+	if a.ID != "urn:oid:2.49.0.1.840.0.1" || a.Event != "Tornado Warning" || a.Severity != Extreme {
+		t.Errorf("first alert is %q, %q, %v", a.ID, a.Event, a.Severity)
+	}
+	if a.Instruction != "TAKE COVER NOW!" || a.Sender != "NWS Norman OK" {
+		t.Errorf("first alert says %q, from %q", a.Instruction, a.Sender)
+	}
+
+	cdt := time.FixedZone("CDT", -5*60*60)
+	if want := time.Date(2025, time.October, 19, 16, 45, 0, 0, cdt); !a.End.Equal(want) {
+		t.Errorf("first alert ends at %v, want %v", a.End, want)
+	}
+
+	// without an end, an alert lasts until it expires
+	if want := time.Date(2025, time.October, 19, 21, 0, 0, 0, cdt); !alerts[1].End.Equal(want) {
+		t.Errorf("second alert ends at %v, want %v", alerts[1].End, want)
+	}
+
+	if worst, ok := mostSevereAlert(alerts, time.Date(2025, time.October, 19, 16, 30, 0, 0, cdt)); !ok || worst.ID != a.ID {
+		t.Errorf("most severe alert is %q", worst.ID)
+	}
+	if worst, ok := mostSevereAlert(alerts, time.Date(2025, time.October, 19, 17, 0, 0, 0, cdt)); !ok || worst.Event != "Wind Advisory" {
+		t.Errorf("after the warning, most severe alert is %q", worst.Event)
+	}
+}
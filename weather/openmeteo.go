@@ -0,0 +1,196 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	openMeteoBaseURL     = "https://api.open-meteo.com"
+	openMeteoURLTemplate = "%s/v1/forecast?latitude=%f&longitude=%f" +
+		"&current=temperature_2m,apparent_temperature,relative_humidity_2m,weather_code,is_day,wind_speed_10m,wind_direction_10m" +
+		"&hourly=temperature_2m,precipitation_probability,precipitation,weather_code,is_day&forecast_hours=24" +
+		"&daily=weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max,sunrise,sunset" +
+		"&wind_speed_unit=ms&timeformat=unixtime&timezone=auto"
+)
+
+// openMeteo uses Open-Meteo, which doesn't need a key
+type openMeteo struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newOpenMeteo() openMeteo {
+	return openMeteo{baseURL: openMeteoBaseURL, client: httpClient}
+}
+
+type openMeteoResponse struct {
+	Current struct {
+		Time        int64   `json:"time"`
+		Temperature float64 `json:"temperature_2m"`
+		FeelsLike   float64 `json:"apparent_temperature"`
+		Humidity    int     `json:"relative_humidity_2m"`
+		WeatherCode int     `json:"weather_code"`
+		IsDay       int     `json:"is_day"`
+		WindSpeed   float64 `json:"wind_speed_10m"`
+		WindDeg     float64 `json:"wind_direction_10m"`
+	} `json:"current"`
+
+	Hourly struct {
+		Time                []int64   `json:"time"`
+		Temperature         []float64 `json:"temperature_2m"`
+		PrecipitationChance []int     `json:"precipitation_probability"`
+		Precipitation       []float64 `json:"precipitation"`
+		WeatherCode         []int     `json:"weather_code"`
+		IsDay               []int     `json:"is_day"`
+	} `json:"hourly"`
+
+	Daily struct {
+		Time                []int64   `json:"time"`
+		WeatherCode         []int     `json:"weather_code"`
+		High                []float64 `json:"temperature_2m_max"`
+		Low                 []float64 `json:"temperature_2m_min"`
+		PrecipitationChance []int     `json:"precipitation_probability_max"`
+		Sunrise             []int64   `json:"sunrise"`
+		Sunset              []int64   `json:"sunset"`
+	} `json:"daily"`
+}
+
+func (openMeteo) Name() string {
+	return "open-meteo"
+}
+
+func (p openMeteo) Report(loc *WeatherLocation) (*Report, error) {
+	var res openMeteoResponse
+	if err := getJSON(p.client, p.Name(), fmt.Sprintf(openMeteoURLTemplate, p.baseURL, loc.Latitude, loc.Longitude), &res); err != nil {
+		return nil, err
+	}
+
+	c := res.Current
+	suffix := "n"
+	if c.IsDay == 1 {
+		suffix = "d"
+	}
+
+	report := &Report{
+		Current: Conditions{
+			Time:        time.Unix(c.Time, 0),
+			Temperature: c.Temperature,
+			FeelsLike:   c.FeelsLike,
+			Humidity:    c.Humidity,
+			WindSpeed:   c.WindSpeed,
+			WindDeg:     c.WindDeg,
+			Description: wmoDescription(c.WeatherCode),
+			Icon:        wmoIcon(c.WeatherCode) + suffix,
+		},
+	}
+
+	h := res.Hourly
+	for i, t := range h.Time {
+		if i >= len(h.Temperature) || i >= len(h.PrecipitationChance) || i >= len(h.Precipitation) || i >= len(h.WeatherCode) || i >= len(h.IsDay) {
+			break
+		}
+
+		suffix := "n"
+		if h.IsDay[i] == 1 {
+			suffix = "d"
+		}
+
+		report.Hourly = append(report.Hourly, HourForecast{
+			Time:                time.Unix(t, 0),
+			Temperature:         h.Temperature[i],
+			PrecipitationChance: h.PrecipitationChance[i],
+			Precipitation:       h.Precipitation[i],
+			Description:         wmoDescription(h.WeatherCode[i]),
+			Icon:                wmoIcon(h.WeatherCode[i]) + suffix,
+		})
+	}
+
+	d := res.Daily
+	for i, t := range d.Time {
+		if i >= len(d.WeatherCode) || i >= len(d.High) || i >= len(d.Low) || i >= len(d.PrecipitationChance) {
+			break
+		}
+
+		report.Daily = append(report.Daily, DayForecast{
+			Date:                time.Unix(t, 0),
+			High:                d.High[i],
+			Low:                 d.Low[i],
+			PrecipitationChance: d.PrecipitationChance[i],
+			Description:         wmoDescription(d.WeatherCode[i]),
+			Icon:                wmoIcon(d.WeatherCode[i]) + "d",
+		})
+	}
+
+	if len(d.Sunrise) > 0 && len(d.Sunset) > 0 {
+		report.Sunrise, report.Sunset = time.Unix(d.Sunrise[0], 0), time.Unix(d.Sunset[0], 0)
+	}
+
+	return report, nil
+}
+
+// wmoIcon returns the OpenWeatherMap icon code (without "d" or "n") for a
+// WMO weather code
+func wmoIcon(code int) string {
+	switch {
+	case code == 0:
+		return "01"
+	case code == 1:
+		return "02"
+	case code == 2:
+		return "03"
+	case code == 3:
+		return "04"
+	case code == 45 || code == 48:
+		return "50"
+	case code >= 51 && code <= 57, code >= 80 && code <= 82:
+		return "09"
+	case code >= 61 && code <= 67:
+		return "10"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "13"
+	case code >= 95:
+		return "11"
+	default:
+		return "03"
+	}
+}
+
+var wmoDescriptions = map[int]string{
+	0:  "clear sky",
+	1:  "mainly clear",
+	2:  "partly cloudy",
+	3:  "overcast",
+	45: "fog",
+	48: "freezing fog",
+	51: "light drizzle",
+	53: "drizzle",
+	55: "heavy drizzle",
+	56: "light freezing drizzle",
+	57: "freezing drizzle",
+	61: "light rain",
+	63: "rain",
+	65: "heavy rain",
+	66: "light freezing rain",
+	67: "freezing rain",
+	71: "light snow",
+	73: "snow",
+	75: "heavy snow",
+	77: "snow grains",
+	80: "light showers",
+	81: "showers",
+	82: "heavy showers",
+	85: "snow showers",
+	86: "heavy snow showers",
+	95: "thunderstorm",
+	96: "thunderstorm with hail",
+	99: "thunderstorm with heavy hail",
+}
+
+func wmoDescription(code int) string {
+	if desc, ok := wmoDescriptions[code]; ok {
+		return desc
+	}
+	return "unknown"
+}
@@ -0,0 +1,51 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenMeteoReport(t *testing.T) {
+	srv := serveTestdata(t, map[string]string{"/v1/forecast": "openmeteo.json"})
+	p := openMeteo{baseURL: srv.URL, client: srv.Client()}
+
+	r, err := p.Report(testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := r.Current
+	if !c.Time.Equal(time.Unix(1760882400, 0)) {
+		t.Errorf("time is %v", c.Time)
+	}
+	if !approx(c.Temperature, 12.4) || !approx(c.FeelsLike, 10.1) {
+		t.Errorf("temperature is %v, feels like %v; want 12.4 and 10.1 °C", c.Temperature, c.FeelsLike)
+	}
+	if !approx(c.WindSpeed, 4.2) || !approx(c.WindDeg, 250) {
+		t.Errorf("wind is %v m/s from %v°; want 4.2 from 250", c.WindSpeed, c.WindDeg)
+	}
+	if c.Humidity != 81 || c.Description != "light rain" || c.Icon != "10d" {
+		t.Errorf("conditions are %d%%, %q, %q", c.Humidity, c.Description, c.Icon)
+	}
+
+	if len(r.Hourly) != 3 {
+		t.Fatalf("%d hours, want 3", len(r.Hourly))
+	}
+	if h := r.Hourly[0]; h.PrecipitationChance != 70 || !approx(h.Precipitation, 0.8) {
+		t.Errorf("first hour has %d%% and %v mm", h.PrecipitationChance, h.Precipitation)
+	}
+	if icon := r.Hourly[2].Icon; icon != "03n" {
+		t.Errorf("last hour's icon is %q, want 03n", icon)
+	}
+
+	if len(r.Daily) != 2 {
+		t.Fatalf("%d days, want 2", len(r.Daily))
+	}
+	if d := r.Daily[1]; !approx(d.High, 15.1) || !approx(d.Low, 6.0) || d.Icon != "01d" {
+		t.Errorf("second day is %v/%v, %q", d.High, d.Low, d.Icon)
+	}
+
+	if !r.Sunrise.Equal(time.Unix(1760852340, 0)) || !r.Sunset.Equal(time.Unix(1760890080, 0)) {
+		t.Errorf("sun times are %v and %v", r.Sunrise, r.Sunset)
+	}
+}
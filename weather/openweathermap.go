@@ -0,0 +1,183 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	openWeatherMapBaseURL             = "https://api.openweathermap.org"
+	openWeatherMapURLTemplate         = "%s/data/2.5/weather?lat=%f&lon=%f&appid=%s&units=metric"
+	openWeatherMapForecastURLTemplate = "%s/data/2.5/forecast?lat=%f&lon=%f&appid=%s&units=metric"
+	openWeatherMapOneCallURLTemplate  = "%s/data/3.0/onecall?lat=%f&lon=%f&appid=%s&exclude=current,minutely,hourly,daily"
+)
+
+// openWeatherMap uses OpenWeatherMap, with the user's API key
+type openWeatherMap struct {
+	key     string
+	baseURL string
+	client  *http.Client
+}
+
+func newOpenWeatherMap(key string) openWeatherMap {
+	return openWeatherMap{key: key, baseURL: openWeatherMapBaseURL, client: httpClient}
+}
+
+func (openWeatherMap) Name() string {
+	return "openweathermap"
+}
+
+func (p openWeatherMap) Report(loc *WeatherLocation) (*Report, error) {
+	var current fullWeatherReport
+	if err := getJSON(p.client, p.Name(), fmt.Sprintf(openWeatherMapURLTemplate, p.baseURL, loc.Latitude, loc.Longitude, p.key), &current); err != nil {
+		return nil, err
+	}
+
+	var forecast forecastReport
+	if err := getJSON(p.client, p.Name(), fmt.Sprintf(openWeatherMapForecastURLTemplate, p.baseURL, loc.Latitude, loc.Longitude, p.key), &forecast); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Current: Conditions{
+			Time:        time.Unix(current.Dt, 0),
+			Temperature: float64(current.Main.Temp),
+			FeelsLike:   float64(current.Main.FeelsLike),
+			Humidity:    current.Main.Humidity,
+			WindSpeed:   float64(current.Wind.Speed),
+			WindDeg:     float64(current.Wind.Deg),
+		},
+		Sunrise: time.Unix(current.Sys.Sunrise, 0),
+		Sunset:  time.Unix(current.Sys.Sunset, 0),
+		Hourly:  hourlyFromForecast(forecast.List),
+		Daily:   dailyFromForecast(forecast.List),
+	}
+	if len(current.Weather) > 0 {
+		report.Current.Description = current.Weather[0].Description
+		report.Current.Icon = current.Weather[0].Icon
+	}
+
+	return report, nil
+}
+
+// Alerts returns alerts from One Call, which needs its own subscription
+func (p openWeatherMap) Alerts(loc *WeatherLocation) ([]Alert, error) {
+	var res struct {
+		Alerts []struct {
+			SenderName  string `json:"sender_name"`
+			Event       string `json:"event"`
+			Start       int64  `json:"start"`
+			End         int64  `json:"end"`
+			Description string `json:"description"`
+		} `json:"alerts"`
+	}
+	if err := getJSON(p.client, p.Name(), fmt.Sprintf(openWeatherMapOneCallURLTemplate, p.baseURL, loc.Latitude, loc.Longitude, p.key), &res); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]Alert, 0, len(res.Alerts))
+	for _, a := range res.Alerts {
+		alerts = append(alerts, Alert{
+			// One Call doesn't identify alerts
+			ID:          fmt.Sprintf("%s/%s/%d", a.SenderName, a.Event, a.Start),
+			Event:       a.Event,
+			Severity:    eventSeverity(a.Event),
+			Description: a.Description,
+			Sender:      a.SenderName,
+			Start:       time.Unix(a.Start, 0),
+			End:         time.Unix(a.End, 0),
+		})
+	}
+
+	return alerts, nil
+}
+
+// eventSeverity guesses how severe an alert is from its name, for One Call,
+// which doesn't say
+func eventSeverity(event string) Severity {
+	event = strings.ToLower(event)
+	switch {
+	case strings.Contains(event, "emergency"):
+		return Extreme
+	case strings.Contains(event, "warning"):
+		return Severe
+	case strings.Contains(event, "watch"):
+		return Moderate
+	case strings.Contains(event, "advisory"), strings.Contains(event, "statement"):
+		return Minor
+	default:
+		return UnknownSeverity
+	}
+}
+
+// hourlyFromForecast returns the next day of a three-hourly forecast
+func hourlyFromForecast(entries []forecastEntry) []HourForecast {
+	var hours []HourForecast
+	end := time.Now().Add(time.Hour * 24)
+	for _, e := range entries {
+		t := time.Unix(e.Dt, 0)
+		if t.After(end) {
+			break
+		}
+
+		h := HourForecast{
+			Time:                t,
+			Temperature:         float64(e.Main.Temp),
+			PrecipitationChance: int(e.Pop*100 + 0.5),
+			Precipitation:       float64(e.Rain.ThreeHours + e.Snow.ThreeHours),
+		}
+		if len(e.Weather) > 0 {
+			h.Description, h.Icon = e.Weather[0].Description, e.Weather[0].Icon
+		}
+		hours = append(hours, h)
+	}
+	return hours
+}
+
+// dailyFromForecast sums up a three-hourly forecast into days. each day is
+// described by its entry closest to noon
+func dailyFromForecast(entries []forecastEntry) []DayForecast {
+	var (
+		days    []DayForecast
+		closest time.Duration
+	)
+
+	for _, e := range entries {
+		t := time.Unix(e.Dt, 0)
+		y, m, d := t.Date()
+		date := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+		temp := float64(e.Main.Temp)
+
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, DayForecast{Date: date, High: temp, Low: temp})
+			closest = -1
+		}
+
+		day := &days[len(days)-1]
+		if pop := int(e.Pop*100 + 0.5); pop > day.PrecipitationChance {
+			day.PrecipitationChance = pop
+		}
+		if temp > day.High {
+			day.High = temp
+		}
+		if temp < day.Low {
+			day.Low = temp
+		}
+
+		fromNoon := t.Sub(date.Add(time.Hour * 12))
+		if fromNoon < 0 {
+			fromNoon = -fromNoon
+		}
+		if len(e.Weather) > 0 && (closest < 0 || fromNoon < closest) {
+			closest = fromNoon
+			day.Description = e.Weather[0].Description
+			if icon := e.Weather[0].Icon; len(icon) >= 2 {
+				day.Icon = icon[:2] + "d"
+			}
+		}
+	}
+
+	return days
+}
@@ -0,0 +1,74 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenWeatherMapReport(t *testing.T) {
+	srv := serveTestdata(t, map[string]string{
+		"/data/2.5/weather":  "openweathermap_weather.json",
+		"/data/2.5/forecast": "openweathermap_forecast.json",
+	})
+	p := openWeatherMap{key: "key", baseURL: srv.URL, client: srv.Client()}
+
+	r, err := p.Report(testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := r.Current
+	if !approx(c.Temperature, 12.4) || !approx(c.FeelsLike, 10.1) {
+		t.Errorf("temperature is %v, feels like %v; want 12.4 and 10.1 °C", c.Temperature, c.FeelsLike)
+	}
+	if !approx(c.WindSpeed, 4.2) || !approx(c.WindDeg, 250) {
+		t.Errorf("wind is %v m/s from %v°; want 4.2 from 250", c.WindSpeed, c.WindDeg)
+	}
+	if c.Humidity != 81 || c.Description != "light rain" || c.Icon != "10d" {
+		t.Errorf("conditions are %d%%, %q, %q", c.Humidity, c.Description, c.Icon)
+	}
+	if !r.Sunrise.Equal(time.Unix(1760852340, 0)) || !r.Sunset.Equal(time.Unix(1760890080, 0)) {
+		t.Errorf("sun times are %v and %v", r.Sunrise, r.Sunset)
+	}
+
+	if len(r.Hourly) != 3 {
+		t.Fatalf("%d hours, want 3", len(r.Hourly))
+	}
+	if h := r.Hourly[0]; h.PrecipitationChance != 70 || !approx(h.Precipitation, 0.81) {
+		t.Errorf("first hours have %d%% and %v mm", h.PrecipitationChance, h.Precipitation)
+	}
+
+	if len(r.Daily) == 0 {
+		t.Fatal("no days")
+	}
+	if d := r.Daily[0]; !approx(d.High, 12.9) || d.PrecipitationChance != 70 {
+		t.Errorf("first day is %v with %d%%", d.High, d.PrecipitationChance)
+	}
+}
+
+func TestOpenWeatherMapAlerts(t *testing.T) {
+	srv := serveTestdata(t, map[string]string{"/data/3.0/onecall": "openweathermap_onecall.json"})
+	p := openWeatherMap{key: "key", baseURL: srv.URL, client: srv.Client()}
+
+	alerts, err := p.Alerts(testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("%d alerts, want 2", len(alerts))
+	}
+
+	a := alerts[0]
+	if a.Event != "Tornado Warning" || a.Severity != Severe {
+		t.Errorf("first alert is %q, %v", a.Event, a.Severity)
+	}
+	if !a.Start.Equal(time.Unix(1760908320, 0)) || !a.End.Equal(time.Unix(1760910300, 0)) {
+		t.Errorf("first alert is from %v to %v", a.Start, a.End)
+	}
+	if alerts[1].Severity != Minor {
+		t.Errorf("advisory is %v, want minor", alerts[1].Severity)
+	}
+	if a.ID == alerts[1].ID {
+		t.Error("alerts have the same ID")
+	}
+}
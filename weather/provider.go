@@ -0,0 +1,166 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	requestTimeout = time.Second * 15
+
+	// some services, like the NWS, turn away requests that don't say who's
+	// asking
+	userAgent = "muse-status (https://github.com/muni-corn/muse-status)"
+)
+
+// Provider fetches weather reports from a weather service
+type Provider interface {
+	// Name returns the name of the service, for errors and queries
+	Name() string
+
+	// Report returns the current conditions and forecast at loc
+	Report(loc *WeatherLocation) (*Report, error)
+}
+
+// Report is a weather report, converted from whatever a provider returns.
+// Temperatures are in °C and speeds in m/s; they're converted for display
+type Report struct {
+	Current Conditions
+	Hourly  []HourForecast // starting this hour, for about a day
+	Daily   []DayForecast  // starting today
+
+	Sunrise time.Time // today's
+	Sunset  time.Time
+
+	Alerts []Alert // from the alert provider, if any
+}
+
+// Conditions are the weather at a point in time
+type Conditions struct {
+	Time        time.Time
+	Temperature float64
+	FeelsLike   float64
+	Humidity    int // percent
+	WindSpeed   float64
+	WindDeg     float64 // the direction the wind comes from
+	Description string
+
+	// Icon is an OpenWeatherMap icon code, like "01d", which every provider
+	// translates its conditions to
+	Icon string
+}
+
+// HourForecast is the forecast for an hour, or a few hours for providers
+// that don't forecast every hour
+type HourForecast struct {
+	Time                time.Time
+	Temperature         float64
+	PrecipitationChance int     // percent
+	Precipitation       float64 // mm
+	Description         string
+	Icon                string
+}
+
+// DayForecast is the forecast for a single day
+type DayForecast struct {
+	Date                time.Time
+	High                float64
+	Low                 float64
+	PrecipitationChance int // percent
+	Description         string
+	Icon                string
+}
+
+// NewProvider returns the provider with the given name: "open-meteo",
+// "openweathermap" or "wttr.in". OpenWeatherMap needs an API key
+func NewProvider(name, key string) (Provider, error) {
+	switch name {
+	case "open-meteo", "openmeteo":
+		return newOpenMeteo(), nil
+	case "openweathermap", "owm":
+		if key == "" {
+			return nil, fmt.Errorf("openweathermap needs an API key")
+		}
+		return newOpenWeatherMap(key), nil
+	case "wttr.in", "wttr":
+		return newWTTR(), nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider: %s", name)
+	}
+}
+
+// httpClient is the client that providers use unless they're given another
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// getJSON requests url with client and decodes its JSON response into v
+func getJSON(client *http.Client, provider, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", provider, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %v", provider, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %v", provider, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		// services explain themselves in different fields
+		var explanation struct {
+			Message string `json:"message"`
+			Reason  string `json:"reason"`
+			Detail  string `json:"detail"`
+		}
+		json.Unmarshal(body, &explanation)
+
+		msg := explanation.Message + explanation.Reason + explanation.Detail
+		if msg == "" {
+			msg = http.StatusText(res.StatusCode)
+		}
+		return statusError{provider, res.StatusCode, strings.ToLower(msg)}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%s: %v", provider, err)
+	}
+	return nil
+}
+
+// statusError is a provider answering with an HTTP error
+type statusError struct {
+	provider string
+	code     int
+	msg      string
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.provider, e.msg)
+}
+
+// isAuthError returns true if err is a provider refusing our key, or refusing
+// us without one. asking again won't change its mind
+func isAuthError(err error) bool {
+	var se statusError
+	return errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusForbidden)
+}
+
+// dayOrNight returns the suffix of an icon code, "d" or "n", for whether t
+// is between sunrise and sunset. if either is unknown, it's day
+func dayOrNight(t, sunrise, sunset time.Time) string {
+	if !knownSunTime(sunrise, t) || !knownSunTime(sunset, t) || (!t.Before(sunrise) && t.Before(sunset)) {
+		return "d"
+	}
+	return "n"
+}
@@ -0,0 +1,77 @@
+package weather
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testLocation = &WeatherLocation{Latitude: 52.52, Longitude: 13.42}
+
+// serveTestdata starts a server that responds to each path in routes with a
+// file from testdata, standing in for a weather service
+func serveTestdata(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("%s: User-Agent is %q", r.URL.Path, r.Header.Get("User-Agent"))
+		}
+
+		name, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Errorf("%s: %v", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// approx returns true if a and b are equal to within float32 precision
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-3
+}
+
+func TestGetJSONError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"cod": 401, "message": "Invalid API key. Please see https://openweathermap.org/faq#error401 for more info."}`))
+	}))
+	defer srv.Close()
+
+	var v struct{}
+	err := getJSON(srv.Client(), "openweathermap", srv.URL, &v)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.HasPrefix(err.Error(), "openweathermap: invalid api key") {
+		t.Errorf("error is %q", err)
+	}
+}
+
+func TestGetJSONStatusText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	var v struct{}
+	err := getJSON(srv.Client(), "wttr.in", srv.URL, &v)
+	if err == nil || err.Error() != "wttr.in: bad gateway" {
+		t.Errorf("error is %v", err)
+	}
+}
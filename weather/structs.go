@@ -3,15 +3,38 @@ package weather
 type WeatherLocation struct {
 	Latitude  float32 `json:"latitude"`
 	Longitude float32 `json:"longitude"`
+	Name      string  `json:"name"` // for display, like "Berlin, Germany"; may be empty
 }
 
+// fullWeatherReport is OpenWeatherMap's current weather
 type fullWeatherReport struct {
+	Dt      int64            `json:"dt"`
 	Sys     sunTimeData      `json:"sys"`
 	Weather []weatherDetails `json:"weather"`
 	Main    weatherMain      `json:"main"`
 	Wind    weatherWind      `json:"wind"`
 }
 
+// forecastReport is OpenWeatherMap's five day forecast, in three hour steps
+type forecastReport struct {
+	List []forecastEntry `json:"list"`
+}
+
+type forecastEntry struct {
+	Dt      int64            `json:"dt"`
+	Weather []weatherDetails `json:"weather"`
+	Main    weatherMain      `json:"main"`
+	Wind    weatherWind      `json:"wind"`
+	Pop     float32          `json:"pop"` // probability of precipitation, from 0 to 1
+	Rain    precipitation    `json:"rain"`
+	Snow    precipitation    `json:"snow"`
+}
+
+// precipitation is the amount of rain or snow in the last three hours, in mm
+type precipitation struct {
+	ThreeHours float32 `json:"3h"`
+}
+
 type sunTimeData struct {
 	Sunrise int64 `json:"sunrise"`
 	Sunset  int64 `json:"sunset"`
@@ -28,5 +51,7 @@ type weatherWind struct {
 }
 
 type weatherMain struct {
-	Temp float32 `json:"temp"`
+	Temp      float32 `json:"temp"`
+	FeelsLike float32 `json:"feels_like"`
+	Humidity  int     `json:"humidity"`
 }
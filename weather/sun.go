@@ -0,0 +1,85 @@
+package weather
+
+import (
+	"strings"
+	"time"
+)
+
+// sun times further than this from the report's time aren't for its day.
+// providers send zero times (or the Unix epoch) when the sun doesn't rise or
+// set, like in polar summer and winter
+const maxSunTimeOffset = time.Hour * 36
+
+// knownSunTime returns true if t is a real sunrise or sunset near date. if
+// date is zero, any nonzero t is believed
+func knownSunTime(t, date time.Time) bool {
+	if t.IsZero() || t.Unix() == 0 {
+		return false
+	}
+	if date.IsZero() {
+		return true
+	}
+
+	offset := t.Sub(date)
+	return offset > -maxSunTimeOffset && offset < maxSunTimeOffset
+}
+
+// sunTimes returns the first sunset at or after now, and the sunrise before
+// it. reports only have one day's times, so they're moved a day at a time;
+// sunrise and sunset don't change much from one day to the next. ok is false
+// if the report doesn't have both
+func sunTimes(r *Report, now time.Time) (sunrise, sunset time.Time, ok bool) {
+	if r == nil || !knownSunTime(r.Sunrise, r.Current.Time) || !knownSunTime(r.Sunset, r.Current.Time) {
+		return
+	}
+
+	sunrise, sunset = r.Sunrise, r.Sunset
+	for sunset.Before(now) {
+		sunrise, sunset = sunrise.AddDate(0, 0, 1), sunset.AddDate(0, 0, 1)
+	}
+	for sunset.AddDate(0, 0, -1).After(now) {
+		sunrise, sunset = sunrise.AddDate(0, 0, -1), sunset.AddDate(0, 0, -1)
+	}
+
+	return sunrise, sunset, true
+}
+
+// isDaytime returns true if the sun is up at now. without sunrise and sunset,
+// the sun is up or down all day, so the current conditions' icon decides
+func isDaytime(r *Report, now time.Time) (day, ok bool) {
+	if r == nil {
+		return false, false
+	}
+
+	sunrise, _, ok := sunTimes(r, now)
+	if !ok {
+		return !strings.HasSuffix(r.Current.Icon, "n"), true
+	}
+	return !now.Before(sunrise), true
+}
+
+// nextSunEvent returns the next sunrise or sunset after now
+func nextSunEvent(r *Report, now time.Time) (t time.Time, sunrise, ok bool) {
+	rise, set, ok := sunTimes(r, now)
+	if !ok {
+		return
+	}
+
+	if now.Before(rise) {
+		return rise, true, true
+	}
+	return set, false, true
+}
+
+// sunText returns text like "sunset at 7:42 pm"
+func sunText(r *Report) string {
+	t, sunrise, ok := nextSunEvent(r, time.Now())
+	if !ok {
+		return ""
+	}
+
+	if sunrise {
+		return "sunrise at " + t.Format("3:04 pm")
+	}
+	return "sunset at " + t.Format("3:04 pm")
+}
@@ -0,0 +1,65 @@
+package weather
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	date := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	sunrise := time.Date(2024, time.June, 21, 5, 0, 0, 0, time.UTC)
+	sunset := time.Date(2024, time.June, 21, 21, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name            string
+		sunrise, sunset time.Time
+		icon            string
+		now             time.Time
+		day, known      bool
+	}{
+		{"morning", sunrise, sunset, "01d", date.Add(-time.Hour * 4), true, true},
+		{"before sunrise", sunrise, sunset, "01n", date.Add(-time.Hour * 8), false, true},
+		{"after sunset", sunrise, sunset, "01n", date.Add(time.Hour * 10), false, true},
+		{"next day", sunrise, sunset, "01d", date.AddDate(0, 0, 1), true, true},
+		{"polar day", time.Unix(0, 0), time.Unix(0, 0), "01d", date, true, false},
+		{"polar night", time.Unix(0, 0), time.Unix(0, 0), "13n", date, false, false},
+		{"missing", time.Time{}, time.Time{}, "13n", date, false, false},
+		{"another day", sunrise.AddDate(0, 0, -3), sunset.AddDate(0, 0, -3), "01d", date, true, false},
+	} {
+		r := &Report{
+			Current: Conditions{Time: date, Icon: test.icon},
+			Sunrise: test.sunrise,
+			Sunset:  test.sunset,
+		}
+
+		if _, _, ok := sunTimes(r, test.now); ok != test.known {
+			t.Errorf("%s: sun times known is %v, want %v", test.name, ok, test.known)
+		}
+		if _, _, ok := nextSunEvent(r, test.now); ok != test.known {
+			t.Errorf("%s: next event known is %v, want %v", test.name, ok, test.known)
+		}
+		if day, ok := isDaytime(r, test.now); day != test.day || !ok {
+			t.Errorf("%s: daytime is %v (%v), want %v", test.name, day, ok, test.day)
+		}
+	}
+
+	if _, ok := isDaytime(nil, date); ok {
+		t.Error("daytime is known without a report")
+	}
+}
+
+func TestDayOrNight(t *testing.T) {
+	noon := time.Date(2024, time.December, 21, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		sunrise, sunset time.Time
+		want            string
+	}{
+		{noon.Add(-time.Hour * 4), noon.Add(time.Hour * 4), "d"},
+		{noon.Add(time.Hour), noon.Add(time.Hour * 2), "n"},
+		{time.Unix(0, 0), time.Unix(0, 0), "d"},
+	} {
+		if got := dayOrNight(noon, test.sunrise, test.sunset); got != test.want {
+			t.Errorf("sunrise %v, sunset %v: got %q, want %q", test.sunrise, test.sunset, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/muni-corn/muse-status/utils"
+)
+
+// Units are the units that weather is shown in
+type Units int
+
+// Definitions for Units
+const (
+	Imperial Units = iota // °F and mph
+	Metric                // °C and km/h
+	Standard              // K and m/s
+)
+
+// temperature converts a temperature in °C to u
+func (u Units) temperature(celsius float64) float64 {
+	switch u {
+	case Imperial:
+		return celsius*9/5 + 32
+	case Standard:
+		return celsius + 273.15
+	default:
+		return celsius
+	}
+}
+
+// formatTemperature returns a temperature in °C as text in u, like "72°"
+func (u Units) formatTemperature(celsius float64) string {
+	t := int(math.Round(u.temperature(celsius)))
+	if u == Standard {
+		return fmt.Sprintf("%d K", t)
+	}
+	return fmt.Sprintf("%d°", t)
+}
+
+// formatSpeed returns a speed in m/s as text in u
+func (u Units) formatSpeed(ms float64) string {
+	switch u {
+	case Imperial:
+		return fmt.Sprintf("%.0f mph", ms*2.23694)
+	case Metric:
+		return fmt.Sprintf("%.0f km/h", ms*3.6)
+	default:
+		return fmt.Sprintf("%.0f m/s", ms)
+	}
+}
+
+var compassPoints = []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// compassPoint returns the compass point closest to a direction in degrees
+func compassPoint(deg float64) string {
+	i := int(math.Round(math.Mod(deg, 360)/45)) % len(compassPoints)
+	if i < 0 {
+		i += len(compassPoints)
+	}
+	return compassPoints[i]
+}
+
+// fieldFormatters return the fields that can be shown in the block's text,
+// by name
+var fieldFormatters = map[string]func(r *Report, u Units) string{
+	"temperature": func(r *Report, u Units) string {
+		return u.formatTemperature(r.Current.Temperature)
+	},
+	"feels-like": func(r *Report, u Units) string {
+		return "feels like " + u.formatTemperature(r.Current.FeelsLike)
+	},
+	"humidity": func(r *Report, u Units) string {
+		return fmt.Sprintf("%d%% humidity", r.Current.Humidity)
+	},
+	"wind": func(r *Report, u Units) string {
+		return u.formatSpeed(r.Current.WindSpeed) + " " + compassPoint(r.Current.WindDeg)
+	},
+	"description": func(r *Report, u Units) string {
+		return r.Current.Description
+	},
+	"precipitation": func(r *Report, u Units) string {
+		return precipitationText(r)
+	},
+	"sun": func(r *Report, u Units) string {
+		return sunText(r)
+	},
+}
+
+// checkFields returns an error if any of fields can't be shown
+func checkFields(fields []string) error {
+	for _, f := range fields {
+		if _, ok := fieldFormatters[f]; !ok {
+			return fmt.Errorf("unknown weather field: %s", f)
+		}
+	}
+	return nil
+}
+
+// formatFields joins fields of a report into text, like "Clear sky, 45%
+// humidity"
+func formatFields(r *Report, u Units, fields []string) string {
+	if r == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, f := range fields {
+		if format, ok := fieldFormatters[f]; ok {
+			if text := format(r, u); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+
+	return utils.Capitalize(strings.Join(parts, ", "))
+}
@@ -0,0 +1,200 @@
+package weather
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	wttrBaseURL     = "https://wttr.in"
+	wttrURLTemplate = "%s/%f,%f?format=j1"
+)
+
+// wttr uses wttr.in, which doesn't need a key
+type wttr struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newWTTR() wttr {
+	return wttr{baseURL: wttrBaseURL, client: httpClient}
+}
+
+// wttr.in reports everything as strings
+type wttrResponse struct {
+	CurrentCondition []struct {
+		TempC         string      `json:"temp_C"`
+		FeelsLikeC    string      `json:"FeelsLikeC"`
+		Humidity      string      `json:"humidity"`
+		WeatherCode   string      `json:"weatherCode"`
+		WeatherDesc   []wttrValue `json:"weatherDesc"`
+		WindspeedKmph string      `json:"windspeedKmph"`
+		WinddirDegree string      `json:"winddirDegree"`
+	} `json:"current_condition"`
+
+	Weather []struct {
+		Date     string `json:"date"`
+		MaxTempC string `json:"maxtempC"`
+		MinTempC string `json:"mintempC"`
+
+		Astronomy []struct {
+			Sunrise string `json:"sunrise"`
+			Sunset  string `json:"sunset"`
+		} `json:"astronomy"`
+
+		Hourly []struct {
+			Time         string      `json:"time"` // like "1200" for noon
+			TempC        string      `json:"tempC"`
+			ChanceOfRain string      `json:"chanceofrain"`
+			ChanceOfSnow string      `json:"chanceofsnow"`
+			PrecipMM     string      `json:"precipMM"`
+			WeatherCode  string      `json:"weatherCode"`
+			WeatherDesc  []wttrValue `json:"weatherDesc"`
+		} `json:"hourly"`
+	} `json:"weather"`
+}
+
+type wttrValue struct {
+	Value string `json:"value"`
+}
+
+func (wttr) Name() string {
+	return "wttr.in"
+}
+
+func (p wttr) Report(loc *WeatherLocation) (*Report, error) {
+	var res wttrResponse
+	if err := getJSON(p.client, p.Name(), fmt.Sprintf(wttrURLTemplate, p.baseURL, loc.Latitude, loc.Longitude), &res); err != nil {
+		return nil, err
+	}
+	if len(res.CurrentCondition) == 0 {
+		return nil, fmt.Errorf("%s: no current conditions", p.Name())
+	}
+
+	report := &Report{}
+	now := time.Now()
+	for _, w := range res.Weather {
+		date, err := time.ParseInLocation("2006-01-02", w.Date, time.Local)
+		if err != nil {
+			continue
+		}
+
+		day := DayForecast{
+			Date: date,
+			High: parseFloat(w.MaxTempC),
+			Low:  parseFloat(w.MinTempC),
+		}
+
+		var sunrise, sunset time.Time
+		if len(w.Astronomy) > 0 {
+			sunrise = parseClock(date, w.Astronomy[0].Sunrise)
+			sunset = parseClock(date, w.Astronomy[0].Sunset)
+		}
+		if len(report.Daily) == 0 {
+			report.Sunrise, report.Sunset = sunrise, sunset
+		}
+
+		for _, h := range w.Hourly {
+			// times are hundreds of hours, like "300" for 3 am
+			t := date.Add(time.Duration(parseInt(h.Time)/100) * time.Hour)
+			chance := parseInt(h.ChanceOfRain)
+			if snow := parseInt(h.ChanceOfSnow); snow > chance {
+				chance = snow
+			}
+
+			if chance > day.PrecipitationChance {
+				day.PrecipitationChance = chance
+			}
+
+			// describe the day by noon
+			if h.Time == "1200" {
+				day.Description = wttrDescription(h.WeatherDesc)
+				day.Icon = wwoIcon(parseInt(h.WeatherCode)) + "d"
+			}
+
+			// steps are three hours long, so keep the one we're in
+			if t.Add(time.Hour*3).After(now) && t.Before(now.Add(time.Hour*24)) {
+				report.Hourly = append(report.Hourly, HourForecast{
+					Time:                t,
+					Temperature:         parseFloat(h.TempC),
+					PrecipitationChance: chance,
+					Precipitation:       parseFloat(h.PrecipMM),
+					Description:         wttrDescription(h.WeatherDesc),
+					Icon:                wwoIcon(parseInt(h.WeatherCode)) + dayOrNight(t, sunrise, sunset),
+				})
+			}
+		}
+
+		report.Daily = append(report.Daily, day)
+	}
+
+	c := res.CurrentCondition[0]
+	report.Current = Conditions{
+		Time:        now,
+		Temperature: parseFloat(c.TempC),
+		FeelsLike:   parseFloat(c.FeelsLikeC),
+		Humidity:    parseInt(c.Humidity),
+		WindSpeed:   parseFloat(c.WindspeedKmph) / 3.6,
+		WindDeg:     parseFloat(c.WinddirDegree),
+		Description: wttrDescription(c.WeatherDesc),
+		Icon:        wwoIcon(parseInt(c.WeatherCode)) + dayOrNight(now, report.Sunrise, report.Sunset),
+	}
+
+	return report, nil
+}
+
+func wttrDescription(values []wttrValue) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0].Value
+}
+
+// parseClock returns a time like "06:12 AM" on date. a zero time is returned
+// if it can't be parsed
+func parseClock(date time.Time, clock string) time.Time {
+	t, err := time.Parse("03:04 PM", clock)
+	if err != nil {
+		return time.Time{}
+	}
+	return date.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func parseInt(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+// wwoIcon returns the OpenWeatherMap icon code (without "d" or "n") for a
+// WorldWeatherOnline weather code, which wttr.in uses
+func wwoIcon(code int) string {
+	switch code {
+	case 113:
+		return "01"
+	case 116:
+		return "02"
+	case 119:
+		return "03"
+	case 122:
+		return "04"
+	case 143, 248, 260:
+		return "50"
+	case 176, 263, 266, 293, 296, 353:
+		return "09"
+	case 299, 302, 305, 308, 356, 359:
+		return "10"
+	case 200, 386, 389:
+		return "11"
+	case 179, 182, 185, 227, 230, 281, 284, 311, 314, 317, 320, 323, 326, 329, 332, 335, 338, 350, 362, 365, 368, 371, 374, 377, 392, 395:
+		return "13"
+	default:
+		return "03"
+	}
+}
@@ -0,0 +1,49 @@
+package weather
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWTTRReport(t *testing.T) {
+	path := fmt.Sprintf("/%f,%f", testLocation.Latitude, testLocation.Longitude)
+	srv := serveTestdata(t, map[string]string{path: "wttr.json"})
+	p := wttr{baseURL: srv.URL, client: srv.Client()}
+
+	r, err := p.Report(testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := r.Current
+	if !approx(c.Temperature, 12) || !approx(c.FeelsLike, 10) {
+		t.Errorf("temperature is %v, feels like %v; want 12 and 10 °C", c.Temperature, c.FeelsLike)
+	}
+	if !approx(c.WindSpeed, 5) || !approx(c.WindDeg, 250) {
+		t.Errorf("wind is %v m/s from %v°; want 5 (18 km/h) from 250", c.WindSpeed, c.WindDeg)
+	}
+	if c.Humidity != 81 || c.Description != "Light rain" {
+		t.Errorf("conditions are %d%%, %q", c.Humidity, c.Description)
+	}
+
+	if len(r.Daily) != 2 {
+		t.Fatalf("%d days, want 2", len(r.Daily))
+	}
+	d := r.Daily[0]
+	if !approx(d.High, 13) || !approx(d.Low, 7) || d.PrecipitationChance != 75 {
+		t.Errorf("first day is %v/%v with %d%%", d.High, d.Low, d.PrecipitationChance)
+	}
+	if d.Description != "Light rain" || d.Icon != "09d" {
+		t.Errorf("first day is %q, %q", d.Description, d.Icon)
+	}
+	if snowy := r.Daily[1]; snowy.PrecipitationChance != 5 {
+		t.Errorf("second day has %d%%, want the chance of snow, 5%%", snowy.PrecipitationChance)
+	}
+
+	sunrise := time.Date(2025, time.October, 19, 7, 39, 0, 0, time.Local)
+	sunset := time.Date(2025, time.October, 19, 18, 8, 0, 0, time.Local)
+	if !r.Sunrise.Equal(sunrise) || !r.Sunset.Equal(sunset) {
+		t.Errorf("sun times are %v and %v", r.Sunrise, r.Sunset)
+	}
+}